@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Unquabain/fac/task"
+)
+
+// criticalPath is the "critical-path" command. It prints the
+// longest chain of tasks in the task file, according to how long
+// they took in the last run's journal, and the order the tasks
+// start in when more are ready than can run.
+func criticalPath(args []string) {
+	flags := flag.NewFlagSet(`critical-path`, flag.ExitOnError)
+	flags.Usage = printUsage
+	flags.Var(&vars, `v`, varsUsage)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		printUsage()
+		os.Exit(-1)
+	}
+	yamlFile := flags.Arg(0)
+	file, source := loadTaskFile(yamlFile)
+	if printProblems(yamlFile, source, file.Tasks) {
+		os.Exit(-3)
+	}
+	list := file.Tasks
+
+	journalPath := filepath.Join(filepath.Dir(yamlFile), stateDir, journalFile)
+	history, err := task.ReadJournal(journalPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf(`Can't remember. %v`, err)
+		os.Exit(-2)
+	}
+	estimates := list.Estimate(history)
+	round := func(d time.Duration) time.Duration {
+		return d.Round(10 * time.Millisecond)
+	}
+	origin := func(e task.Estimate) string {
+		if e.Historic {
+			return `last run`
+		}
+		return `guess`
+	}
+
+	path := list.CriticalPath(estimates)
+	if len(path) == 0 {
+		fmt.Println(`No tasks.`)
+		return
+	}
+	fmt.Printf("Critical path: %v\n", round(estimates[path[0]].Remaining))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, name := range path {
+		e := estimates[name]
+		fmt.Fprintf(w, "  %s\t%v\t(%s)\n", name, round(e.Duration), origin(e))
+	}
+	w.Flush()
+
+	tasks := make([]*task.Task, 0, len(list))
+	for _, t := range list {
+		tasks = append(tasks, t)
+	}
+	estimates.Sort(tasks)
+	fmt.Println(``)
+	fmt.Println(`Start order, when tasks are ready at the same time:`)
+	w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "  TASK\tPRIORITY\tESTIMATE\tREMAINING")
+	for _, t := range tasks {
+		e := estimates[t.Name]
+		fmt.Fprintf(w, "  %s\t%d\t%v (%s)\t%v\n", t.Name, t.Priority, round(e.Duration), origin(e), round(e.Remaining))
+	}
+	w.Flush()
+	if len(history) == 0 {
+		fmt.Println(``)
+		fmt.Println(`There's no journal of an earlier run, so every task is guessed to take the same time.`)
+	}
+}
@@ -0,0 +1,97 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/Unquabain/fac/task"
+)
+
+// logState is how far the LogPrinter has got with a Task: the
+// Numbers of the last lines it printed.
+type logState struct {
+	status  task.Status
+	attempt int
+	stdOut  int
+	stdErr  int
+}
+
+// LogPrinter is the plain-text alternative to the TaskLayoutManager.
+// Instead of drawing windows, it streams each Task's STDOUT and
+// STDERR line by line, prefixed with the Task's name, along with
+// every change in the Task's status. It's meant for CI, cron and
+// anywhere else fac's output isn't a terminal.
+type LogPrinter struct {
+	Out    io.Writer
+	Err    io.Writer
+	states map[string]*logState
+	mtx    sync.Mutex
+}
+
+// NewLogPrinter creates a new LogPrinter that writes status changes
+// and STDOUT lines to out, and STDERR lines to err.
+func NewLogPrinter(out, err io.Writer) *LogPrinter {
+	return &LogPrinter{
+		Out:    out,
+		Err:    err,
+		states: make(map[string]*logState),
+	}
+}
+
+func (lp *LogPrinter) state(t *task.Task) *logState {
+	st, ok := lp.states[t.Name]
+	if !ok {
+		st = &logState{status: task.StatusNotRun, attempt: 1}
+		lp.states[t.Name] = st
+	}
+	return st
+}
+
+// printLines prints every line that hasn't been printed yet, and
+// advances last to the Number of the last one. Lines that were
+// dropped from memory before they could be printed are counted
+// instead.
+func (lp *LogPrinter) printLines(w io.Writer, name string, lines []task.Line, last *int) {
+	for _, line := range lines {
+		if line.Number <= *last {
+			continue
+		}
+		if skipped := line.Number - *last - 1; skipped > 0 {
+			fmt.Fprintf(w, "[%s] (%d lines dropped)\n", name, skipped)
+		}
+		fmt.Fprintf(w, "[%s] %s\n", name, line.Text)
+		*last = line.Number
+	}
+}
+
+// Handle satisfies the handler callback of task.TaskList.RunAll.
+// It is safe to call from several goroutines at once.
+func (lp *LogPrinter) Handle(t *task.Task) {
+	lp.mtx.Lock()
+	defer lp.mtx.Unlock()
+	st := lp.state(t)
+	status := t.GetStatus()
+
+	// The handler is called once the output of each attempt has
+	// been flushed, so by the time the next one starts, it's all
+	// been printed.
+	if attempt := t.GetAttempt(); attempt != st.attempt {
+		fmt.Fprintf(lp.Out, "[%s] Retrying (attempt %d/%d)\n", t.Name, attempt, t.Retries+1)
+		st.attempt = attempt
+		st.stdOut, st.stdErr = 0, 0
+	}
+
+	lp.printLines(lp.Out, t.Name, t.GetStdOutLinesSince(st.stdOut), &st.stdOut)
+	lp.printLines(lp.Err, t.Name, t.GetStdErrLinesSince(st.stdErr), &st.stdErr)
+
+	if status != st.status {
+		fmt.Fprintf(lp.Out, "[%s] %s\n", t.Name, status)
+		if status.IsFailure() {
+			for _, failure := range t.GetFailures() {
+				fmt.Fprintf(lp.Out, "[%s]   %s: %s\n", t.Name, failure.Reason, failure)
+			}
+		}
+		st.status = status
+	}
+}
@@ -0,0 +1,106 @@
+package display
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/Unquabain/fac/task"
+	"gopkg.in/yaml.v2"
+)
+
+var logYAML = `---
+Say Hello:
+  command: echo
+  args:
+    - hello
+    - world
+`
+
+func TestLogPrinter(t *testing.T) {
+	list := make(task.TaskList)
+	if err := yaml.Unmarshal([]byte(logYAML), &list); err != nil {
+		t.Fatalf(`could not create example TaskList: %v`, err)
+	}
+	out := new(strings.Builder)
+	err := new(strings.Builder)
+	lp := NewLogPrinter(out, err)
+	if err := list.RunAll(context.Background(), task.RunOptions{}, lp.Handle); err != nil {
+		t.Fatalf(`could not run example TaskList: %v`, err)
+	}
+	expected := `[Say Hello] Queued
+[Say Hello] Running
+[Say Hello] hello world
+[Say Hello] Succeeded
+`
+	if actual := out.String(); actual != expected {
+		t.Fatalf("expected:\n%s\nactual:\n%s", expected, actual)
+	}
+	if actual := err.String(); actual != `` {
+		t.Fatalf(`expected no STDERR; received %q`, actual)
+	}
+}
+
+func TestLogPrinterFailures(t *testing.T) {
+	list := make(task.TaskList)
+	if err := yaml.Unmarshal([]byte(`---
+Fail:
+  command: sh
+  args: [-c, exit 3]
+`), &list); err != nil {
+		t.Fatalf(`could not create example TaskList: %v`, err)
+	}
+	out := new(strings.Builder)
+	lp := NewLogPrinter(out, new(strings.Builder))
+	if err := list.RunAll(context.Background(), task.RunOptions{}, lp.Handle); err != nil {
+		t.Fatalf(`could not run example TaskList: %v`, err)
+	}
+	expected := `[Fail] Failed
+[Fail]   returnCode: return code was 3, expected 0
+`
+	if actual := out.String(); !strings.HasSuffix(actual, expected) {
+		t.Fatalf("expected to end with:\n%s\nactual:\n%s", expected, actual)
+	}
+}
+
+func TestLogPrinterLines(t *testing.T) {
+	out := new(strings.Builder)
+	lp := NewLogPrinter(out, out)
+	last := 0
+	lp.printLines(out, `T`, []task.Line{{Number: 1, Text: `one`}}, &last)
+	if actual := last; actual != 1 {
+		t.Fatalf(`expected last to be 1; was %d`, actual)
+	}
+	lp.printLines(out, `T`, []task.Line{{Number: 1, Text: `one`}, {Number: 2, Text: `two`}}, &last)
+	lp.printLines(out, `T`, []task.Line{{Number: 5, Text: `five`}}, &last)
+	expected := `[T] one
+[T] two
+[T] (2 lines dropped)
+[T] five
+`
+	if actual := out.String(); actual != expected {
+		t.Fatalf("expected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
+
+// BenchmarkLogPrinter prints a task that prints a lot of lines.
+// The time per line should stay about the same however many lines
+// there are.
+func BenchmarkLogPrinter(b *testing.B) {
+	for _, lines := range []int{20000, 80000} {
+		b.Run(fmt.Sprint(lines), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				list := make(task.TaskList)
+				if err := yaml.Unmarshal([]byte(fmt.Sprintf("---\nCount:\n  command: seq\n  args: [%q]\n", fmt.Sprint(lines))), &list); err != nil {
+					b.Fatalf(`could not create example TaskList: %v`, err)
+				}
+				lp := NewLogPrinter(ioutil.Discard, ioutil.Discard)
+				if err := list.RunAll(context.Background(), task.RunOptions{}, lp.Handle); err != nil {
+					b.Fatalf(`could not run example TaskList: %v`, err)
+				}
+			}
+		})
+	}
+}
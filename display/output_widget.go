@@ -1,6 +1,13 @@
 package display
 
-import "github.com/jroimartin/gocui"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Unquabain/fac/task"
+	"github.com/jroimartin/gocui"
+)
 
 // OutputWidgetChannel is an enum for distinguising between
 // STDOUT and STDERR.
@@ -15,11 +22,87 @@ func (owc OutputWidgetChannel) String() string {
 	return string(owc)
 }
 
+// maxOutputText is the most text an OutputWidget keeps. Once it
+// has more, the older half is let go.
+const maxOutputText = task.DefaultOutputLimit
+
+// outputGeneration tells one attempt at running a Task from
+// another, so that an OutputWidget starts over with each.
+type outputGeneration struct {
+	attempt int
+	started time.Time
+}
+
 // OutputWidget is a displayable widget for representing the
 // accumulated output of STDOUT or STDERR.
+//
+// It reads the lines of output as they come, keeping what it's
+// read, so that each Layout only reads, and writes to its view,
+// what's new.
 type OutputWidget struct {
 	Widget
 	Channel OutputWidgetChannel
+
+	generation outputGeneration
+	text       strings.Builder
+	// last is the Number of the last line read.
+	last int
+	// done is set once the Task has finished and anything
+	// that goes after its output has been added.
+	done bool
+	// written is how much of text is in the view. The view is
+	// cleared and written again when rewrite is set.
+	written int
+	rewrite bool
+}
+
+// update reads what the Task has printed since the last time.
+func (ow *OutputWidget) update(t *task.Task) {
+	generation := outputGeneration{attempt: t.GetAttempt(), started: t.GetStarted()}
+	if generation != ow.generation {
+		ow.generation = generation
+		ow.text.Reset()
+		ow.last, ow.done, ow.rewrite = 0, false, true
+	}
+	if ow.done {
+		return
+	}
+	// Read the status first, so that nothing printed before it
+	// changed is missed.
+	status := t.GetStatus()
+	var lines []task.Line
+	switch ow.Channel {
+	case OWCStdOut:
+		lines = t.GetStdOutLinesSince(ow.last)
+	case OWCStdErr:
+		lines = t.GetStdErrLinesSince(ow.last)
+	}
+	for _, line := range lines {
+		if skipped := line.Number - ow.last - 1; skipped > 0 {
+			fmt.Fprintf(&ow.text, "(%d lines dropped)\n", skipped)
+		}
+		ow.text.WriteString(line.Text)
+		if !line.Continued {
+			ow.text.WriteByte('\n')
+		}
+		ow.last = line.Number
+	}
+	if status.IsFinal() {
+		if ow.Channel == OWCStdErr {
+			ow.text.WriteString(failuresText(t))
+		}
+		ow.done = true
+	}
+	if ow.text.Len() > maxOutputText {
+		text := ow.text.String()
+		text = text[len(text)-maxOutputText/2:]
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[i+1:]
+		}
+		ow.text.Reset()
+		ow.text.WriteString(text)
+		ow.rewrite = true
+	}
 }
 
 func (sw *OutputWidget) viewName() string {
@@ -29,7 +112,7 @@ func (sw *OutputWidget) viewName() string {
 // Layout satisfies the gocui.Manager interface by
 // containing the drawing logic for the widget.
 func (ow *OutputWidget) Layout(g *gocui.Gui) error {
-	return ow.Widget.Layout(
+	v, created, err := ow.Widget.setView(
 		ow.viewName(),
 		g,
 		func(v *gocui.View) {
@@ -48,6 +131,18 @@ func (ow *OutputWidget) Layout(g *gocui.Gui) error {
 			}
 		},
 	)
+	if err != nil {
+		return err
+	}
+	if created || ow.rewrite {
+		v.Clear()
+		fmt.Fprint(v, ` `)
+		ow.written, ow.rewrite = 0, false
+	}
+	text := ow.text.String()
+	fmt.Fprint(v, text[ow.written:])
+	ow.written = len(text)
+	return ow.Widget.setOrigin(v)
 }
 
 // Unlayout removes the view from gocui.Gui's internal
@@ -2,6 +2,7 @@ package display
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/Unquabain/fac/task"
 )
@@ -27,16 +28,28 @@ func (r OutputWidgetRegistry) makeOutputWidget(dims *layoutDims, task *task.Task
 	}
 	sow.W = dims.outputWidth
 	sow.X = dims.taskGutter + 1
-	switch channel {
-	case OWCStdOut:
-		sow.Stringer = stringerAdapter(task.GetStdOut())
-	case OWCStdErr:
-		sow.Stringer = stringerAdapter(task.GetStdErr())
+	if channel == OWCStdErr {
 		sow.X += dims.outputWidth + 1
 	}
+	sow.update(task)
 	return sow
 }
 
+// failuresText lists why the Task failed, to go after its STDERR.
+// It's empty if it didn't.
+func failuresText(t *task.Task) string {
+	failures := t.GetFailures()
+	if !t.GetStatus().IsFailure() || len(failures) == 0 {
+		return ``
+	}
+	text := new(strings.Builder)
+	text.WriteString("\n--- failed ---\n")
+	for _, failure := range failures {
+		fmt.Fprintf(text, "%s: %s\n", failure.Reason, failure)
+	}
+	return text.String()
+}
+
 func (r OutputWidgetRegistry) makeStdOutWidget(dims *layoutDims, task *task.Task) *OutputWidget {
 	return r.makeOutputWidget(dims, task, OWCStdOut)
 }
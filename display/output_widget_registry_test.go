@@ -1,6 +1,7 @@
 package display
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -58,3 +59,43 @@ func TestOutputWidgetRegistryTest(t *testing.T) {
 		t.Fatal(`expected to receive different widget back: received the same`)
 	}
 }
+
+func TestOutputWidgetUpdate(t *testing.T) {
+	list := make(task.TaskList)
+	if err := yaml.Unmarshal([]byte(`---
+Fail:
+  run: |
+    echo one
+    echo two >&2
+    exit 3
+`), &list); err != nil {
+		t.Fatalf(`could not create example TaskList: %v`, err)
+	}
+	s := list[`Fail`]
+	owr := make(OutputWidgetRegistry)
+	ld := newLayoutDims(240, 100)
+	sow := owr.makeStdOutWidget(ld, s)
+	if actual := sow.text.String(); actual != `` {
+		t.Fatalf(`expected no output before running; got %q`, actual)
+	}
+	if err := list.RunAll(context.Background(), task.RunOptions{}, func(*task.Task) {}); err != nil {
+		t.Fatalf(`could not run example TaskList: %v`, err)
+	}
+	for i := 0; i < 2; i++ {
+		sow = owr.makeStdOutWidget(ld, s)
+		if actual := sow.text.String(); actual != "one\n" {
+			t.Fatalf(`expected STDOUT to be read once; got %q`, actual)
+		}
+	}
+	owr.makeStdErrWidget(ld, s)
+	sew := owr.makeStdErrWidget(ld, s)
+	expected := "two\n\n--- failed ---\nreturnCode: return code was 3, expected 0\n"
+	if actual := sew.text.String(); actual != expected {
+		t.Fatalf(`expected STDERR and why the task failed; got %q`, actual)
+	}
+
+	list.Rerun(s, false)
+	if sow = owr.makeStdOutWidget(ld, s); sow.text.String() != `` || !sow.rewrite {
+		t.Fatalf(`expected the output of a task that's run again to start over; got %q`, sow.text.String())
+	}
+}
@@ -32,11 +32,29 @@ const (
 // running tasks. Once it's done, you can use the arrow
 // keys to navigate through the completed tasks and
 // examine their output.
+//
+// Whether they're running or not, the focused task can
+// be run again, cancelled or skipped from the task list,
+// and the output columns can be swapped for a timeline of
+// the run.
 type TaskLayoutManager struct {
 	task.TaskList
 	IsFinished bool
 	FocusColumn
-	FocusRow      int
+	FocusRow int
+
+	// ShowTimeline shows a Gantt chart of when each task
+	// ran in place of their output.
+	ShowTimeline bool
+
+	// OnRerun is called when tasks have been reset to run
+	// again, so that they can be run if nothing is running.
+	OnRerun func()
+
+	// OnToggleTimeline is called when the timeline is shown or
+	// hidden, with whether it's shown.
+	OnToggleTimeline func(shown bool)
+
 	outputWidgets OutputWidgetRegistry
 }
 
@@ -100,7 +118,24 @@ func (slm *TaskLayoutManager) SetFocusStdErr() {
 	slm.FocusColumn = FCStdErr
 }
 
-func (slm *TaskLayoutManager) setStatusKeybindings(w *StatusWidget, g *gocui.Gui) {
+// ToggleTimeline switches between showing the tasks' output
+// and the timeline of the run.
+func (slm *TaskLayoutManager) ToggleTimeline() {
+	slm.ShowTimeline = !slm.ShowTimeline
+	if slm.OnToggleTimeline != nil {
+		slm.OnToggleTimeline(slm.ShowTimeline)
+	}
+}
+
+// Rerun resets the task to run again, along with everything
+// downstream of it if downstream is set.
+func (slm *TaskLayoutManager) Rerun(t *task.Task, downstream bool) {
+	if len(slm.TaskList.Rerun(t, downstream)) > 0 && slm.OnRerun != nil {
+		slm.OnRerun()
+	}
+}
+
+func (slm *TaskLayoutManager) setStatusKeybindings(w *StatusWidget, t *task.Task, g *gocui.Gui) {
 	g.DeleteKeybindings(w.viewName())
 	g.SetKeybinding(
 		w.viewName(),
@@ -127,7 +162,58 @@ func (slm *TaskLayoutManager) setStatusKeybindings(w *StatusWidget, g *gocui.Gui
 		gocui.KeyArrowRight,
 		gocui.ModNone,
 		func(gg *gocui.Gui, v *gocui.View) error {
-			slm.SetFocusStdOut()
+			if slm.IsFinished && !slm.ShowTimeline {
+				slm.SetFocusStdOut()
+				slm.Update(gg)
+			}
+			return nil
+		},
+	)
+	g.SetKeybinding(
+		w.viewName(),
+		'r',
+		gocui.ModNone,
+		func(gg *gocui.Gui, v *gocui.View) error {
+			slm.Rerun(t, false)
+			slm.Update(gg)
+			return nil
+		},
+	)
+	g.SetKeybinding(
+		w.viewName(),
+		'R',
+		gocui.ModNone,
+		func(gg *gocui.Gui, v *gocui.View) error {
+			slm.Rerun(t, true)
+			slm.Update(gg)
+			return nil
+		},
+	)
+	g.SetKeybinding(
+		w.viewName(),
+		'c',
+		gocui.ModNone,
+		func(gg *gocui.Gui, v *gocui.View) error {
+			t.Cancel()
+			return nil
+		},
+	)
+	g.SetKeybinding(
+		w.viewName(),
+		's',
+		gocui.ModNone,
+		func(gg *gocui.Gui, v *gocui.View) error {
+			slm.TaskList.Skip(t)
+			slm.Update(gg)
+			return nil
+		},
+	)
+	g.SetKeybinding(
+		w.viewName(),
+		't',
+		gocui.ModNone,
+		func(gg *gocui.Gui, v *gocui.View) error {
+			slm.ToggleTimeline()
 			slm.Update(gg)
 			return nil
 		},
@@ -276,8 +362,11 @@ func (slm *TaskLayoutManager) setStderrKeybindings(sew *OutputWidget, g *gocui.G
 // The main drawing logic of the manager.
 func (slm *TaskLayoutManager) Layout(g *gocui.Gui) error {
 	debugger.Reset()
-	if !slm.IsFinished {
-		slm.IsFinished = slm.TaskList.IsFinished()
+	// Tasks can be reset to run again, so it can go back to
+	// not being finished.
+	slm.IsFinished = slm.TaskList.IsFinished()
+	if !slm.IsFinished || slm.ShowTimeline {
+		slm.FocusColumn = FCTaskList
 	}
 	dims := newLayoutDims(g.Size())
 	sorted := slm.sorted()
@@ -288,16 +377,16 @@ func (slm *TaskLayoutManager) Layout(g *gocui.Gui) error {
 		slm.outputWidgets = make(OutputWidgetRegistry)
 	}
 	taskGutterY := dims.widgetYIterator()
-	for pos, task := range slm.sorted() {
-		w := newStatusWidget(task, dims.taskGutter, taskGutterY)
+	for pos, s := range slm.sorted() {
+		w := newStatusWidget(s, dims.taskGutter, taskGutterY)
 		g.DeleteKeybindings(w.viewName())
 		var (
 			sow *OutputWidget
 			sew *OutputWidget
 		)
-		sow = slm.outputWidgets.makeStdOutWidget(dims, task)
-		sew = slm.outputWidgets.makeStdErrWidget(dims, task)
-		if slm.showConsole(pos, task) {
+		sow = slm.outputWidgets.makeStdOutWidget(dims, s)
+		sew = slm.outputWidgets.makeStdErrWidget(dims, s)
+		if slm.showConsole(pos, s) {
 			stdoutWidgets = append(stdoutWidgets, sow)
 			stderrWidgets = append(stderrWidgets, sew)
 			sow.Attribute = 0
@@ -314,8 +403,8 @@ func (slm *TaskLayoutManager) Layout(g *gocui.Gui) error {
 				} else {
 					sew.Focus = false
 				}
-				defer func(w *StatusWidget, sow *OutputWidget, sew *OutputWidget) {
-					slm.setStatusKeybindings(w, g)
+				defer func(w *StatusWidget, t *task.Task, sow *OutputWidget, sew *OutputWidget) {
+					slm.setStatusKeybindings(w, t, g)
 					slm.setStdoutKeybindings(sow, g)
 					slm.setStderrKeybindings(sew, g)
 					viewName := ``
@@ -328,7 +417,7 @@ func (slm *TaskLayoutManager) Layout(g *gocui.Gui) error {
 						viewName = sew.viewName()
 					}
 					g.SetCurrentView(viewName)
-				}(w, sow, sew)
+				}(w, s, sow, sew)
 			} else {
 				w.Focus = false
 			}
@@ -338,9 +427,30 @@ func (slm *TaskLayoutManager) Layout(g *gocui.Gui) error {
 				sew.Unlayout(g)
 			}(sow, sew)
 		}
+		if !slm.IsFinished && pos == slm.FocusRow {
+			w.Focus = true
+			defer func(w *StatusWidget, t *task.Task) {
+				slm.setStatusKeybindings(w, t, g)
+				g.SetCurrentView(w.viewName())
+			}(w, s)
+		}
 		w.Layout(g)
 	}
 
+	timeline := newTimelineWidget(dims)
+	if slm.ShowTimeline {
+		timeline.chart(sorted)
+		for i := range stdoutWidgets {
+			stdoutWidgets[i].Unlayout(g)
+			stderrWidgets[i].Unlayout(g)
+		}
+		if err := timeline.Layout(g); err != nil {
+			return fmt.Errorf(`couldn't layout the timeline: %w`, err)
+		}
+		return nil
+	}
+	timeline.Unlayout(g)
+
 	if len(stdoutWidgets) == 0 {
 		return nil
 	}
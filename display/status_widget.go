@@ -1,6 +1,9 @@
 package display
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/Unquabain/fac/task"
 	"github.com/jroimartin/gocui"
 )
@@ -9,6 +12,25 @@ import (
 // a Task in the left-hand column.
 type StatusWidget Widget
 
+// statusText describes the Task's status as of now, including which
+// attempt it's on if it can be retried, how long it's been running
+// for, and the first reason it failed if it did.
+func statusText(t *task.Task, now time.Time) fmt.Stringer {
+	status := t.GetStatus()
+	attempt := t.GetAttempt()
+	text := status.String()
+	if t.Retries > 0 && status != task.StatusNotRun && (attempt > 1 || status == task.StatusRunning) {
+		text = fmt.Sprintf(`%s (attempt %d/%d)`, status, attempt, t.Retries+1)
+	}
+	if !t.GetStarted().IsZero() {
+		text = fmt.Sprintf(`%s %s`, text, formatElapsed(t.GetElapsed(now)))
+	}
+	if failures := t.GetFailures(); status.IsFailure() && len(failures) > 0 {
+		text = fmt.Sprintf(`%s: %s`, text, failures[0])
+	}
+	return stringerAdapter(text)
+}
+
 func newStatusWidget(t *task.Task, width int, yIter func() int) *StatusWidget {
 	w := new(StatusWidget)
 	w.Title = t.Name
@@ -17,12 +39,12 @@ func newStatusWidget(t *task.Task, width int, yIter func() int) *StatusWidget {
 	w.H = 2
 	w.W = width
 	status := t.GetStatus()
-	w.Stringer = status
+	w.Stringer = statusText(t, time.Now())
 	if !status.IsOK() {
 		w.Attribute = gocui.ColorRed
 	} else if status == task.StatusRunning {
 		w.Attribute = gocui.ColorYellow
-	} else if status == task.StatusSucceeded {
+	} else if status.IsSuccess() {
 		w.Attribute = gocui.ColorGreen
 	}
 	w.Focus = false
@@ -42,6 +64,10 @@ func (sw *StatusWidget) Layout(g *gocui.Gui) error {
 		func(v *gocui.View) {
 			if sw.Focus {
 				v.BgColor = sw.Attribute
+				if v.BgColor == 0 {
+					// Not run yet: no color of its own.
+					v.BgColor = gocui.ColorWhite
+				}
 				v.FgColor = gocui.ColorBlack
 			} else {
 				v.BgColor = 0
@@ -0,0 +1,173 @@
+package display
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Unquabain/fac/task"
+	"github.com/jroimartin/gocui"
+)
+
+// formatElapsed rounds a duration to something readable at a
+// glance: tenths of a second for the first minute, then seconds.
+func formatElapsed(d time.Duration) string {
+	if d < time.Minute {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
+
+// fit pads or cuts text to exactly width characters.
+func fit(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return text + strings.Repeat(` `, width-len(runes))
+}
+
+// ganttChart draws when each Task ran, as of now, as a bar on a
+// shared time axis width characters wide, so that the Tasks that
+// held the others up stand out. Tasks are listed in the order they
+// started in, and the ones that haven't come last.
+func ganttChart(tasks []*task.Task, width int, now time.Time) string {
+	type row struct {
+		task              *task.Task
+		started, finished time.Time
+	}
+	rows := make([]row, len(tasks))
+	var start, end time.Time
+	nameWidth := 1
+	for i, t := range tasks {
+		r := row{task: t, started: t.GetStarted(), finished: t.GetFinished()}
+		if !r.started.IsZero() && r.finished.IsZero() {
+			r.finished = now
+		}
+		if !r.started.IsZero() && (start.IsZero() || r.started.Before(start)) {
+			start = r.started
+		}
+		if r.finished.After(end) {
+			end = r.finished
+		}
+		if n := len([]rune(t.Name)); n > nameWidth {
+			nameWidth = n
+		}
+		rows[i] = r
+	}
+	if start.IsZero() {
+		return `Nothing has run yet.`
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i].started, rows[j].started
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return rows[i].task.Order < rows[j].task.Order
+	})
+
+	if nameWidth > width/4 {
+		nameWidth = width / 4
+	}
+	// The name, the bar between two rules, and the elapsed time
+	// and status after it.
+	const tailWidth = 24
+	barWidth := width - nameWidth - tailWidth - 3
+	if barWidth < 10 {
+		barWidth = 10
+	}
+	span := end.Sub(start)
+	column := func(t time.Time) int {
+		if span <= 0 {
+			return 0
+		}
+		return int(int64(barWidth) * int64(t.Sub(start)) / int64(span))
+	}
+
+	total := formatElapsed(span)
+	chart := new(strings.Builder)
+	fmt.Fprintf(chart, "Total %s\n", total)
+	fmt.Fprintf(chart, "%s  0s%s%s\n", fit(``, nameWidth), strings.Repeat(` `, max(barWidth-2-len(total), 1)), total)
+	for _, r := range rows {
+		bar := []rune(strings.Repeat(` `, barWidth))
+		if !r.started.IsZero() {
+			fill := '█'
+			if r.task.GetStatus() == task.StatusRunning {
+				fill = '▒'
+			}
+			from, to := column(r.started), column(r.finished)
+			if to <= from {
+				to = from + 1
+			}
+			if to > barWidth {
+				to = barWidth
+				from = min(from, to-1)
+			}
+			for i := from; i < to; i++ {
+				bar[i] = fill
+			}
+		}
+		tail := r.task.GetStatus().String()
+		if !r.started.IsZero() {
+			tail = fmt.Sprintf(`%s %s`, formatElapsed(r.finished.Sub(r.started)), tail)
+		}
+		fmt.Fprintf(chart, "%s │%s│ %s\n", fit(r.task.Name, nameWidth), string(bar), tail)
+	}
+	return chart.String()
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// TimelineWidget is a widget that draws a Gantt chart of the
+// run in place of the STDOUT and STDERR columns.
+type TimelineWidget Widget
+
+func newTimelineWidget(dims *layoutDims) *TimelineWidget {
+	w := new(TimelineWidget)
+	w.Title = `Timeline`
+	w.X = dims.taskGutter + 1
+	w.Y = 0
+	w.W = dims.maxX - w.X - 1
+	w.H = dims.maxY - 1
+	return w
+}
+
+// chart draws the timeline of the Tasks as of now.
+func (tw *TimelineWidget) chart(tasks []*task.Task) {
+	tw.Stringer = stringerAdapter(ganttChart(tasks, tw.W-2, time.Now()))
+}
+
+func (tw *TimelineWidget) viewName() string {
+	return formatViewName(tw.Title, `timeline`)
+}
+
+// Layout satisfies the gocui.Manager interface, and
+// contains the graphical logic.
+func (tw *TimelineWidget) Layout(g *gocui.Gui) error {
+	return (*Widget)(tw).Layout(tw.viewName(), g, func(v *gocui.View) {})
+}
+
+// Unlayout removes the view from gocui.Gui's internal
+// memory.
+func (tw *TimelineWidget) Unlayout(g *gocui.Gui) error {
+	if err := g.DeleteView(tw.viewName()); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package display
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Unquabain/fac/task"
+	"gopkg.in/yaml.v2"
+)
+
+var timelineYAML = `---
+Second:
+  command: sleep
+  args: ["0.1"]
+  dependencies:
+    - First
+First:
+  command: sleep
+  args: ["0.1"]
+`
+
+func TestGanttChart(t *testing.T) {
+	list := make(task.TaskList)
+	if err := yaml.Unmarshal([]byte(timelineYAML), &list); err != nil {
+		t.Fatalf(`could not create example TaskList: %v`, err)
+	}
+	tasks := []*task.Task{list[`Second`], list[`First`]}
+	if actual := ganttChart(tasks, 80, time.Now()); actual != `Nothing has run yet.` {
+		t.Fatalf(`expected nothing to chart before running; got %q`, actual)
+	}
+	if err := list.RunAll(context.Background(), task.RunOptions{}, func(*task.Task) {}); err != nil {
+		t.Fatalf(`could not run example TaskList: %v`, err)
+	}
+	lines := strings.Split(strings.TrimSuffix(ganttChart(tasks, 80, time.Now()), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a total, an axis and a row for each task; got:\n%s", strings.Join(lines, "\n"))
+	}
+	if !strings.HasPrefix(lines[2], `First `) || !strings.HasPrefix(lines[3], `Second`) {
+		t.Fatalf("expected the tasks in the order they started; got:\n%s", strings.Join(lines, "\n"))
+	}
+	// Second can't start before First has finished, so their bars
+	// shouldn't overlap.
+	first, second := strings.Index(lines[2], `█`), strings.Index(lines[3], `█`)
+	if first < 0 || second <= first {
+		t.Fatalf("expected Second's bar to start after First's; got:\n%s", strings.Join(lines, "\n"))
+	}
+	for _, line := range lines[2:] {
+		if len([]rune(line)) > 80 {
+			t.Fatalf(`expected rows to fit in 80 characters; %q doesn't`, line)
+		}
+	}
+}
+
+func TestFormatElapsed(t *testing.T) {
+	expect := func(d time.Duration, expected string) {
+		t.Helper()
+		if actual := formatElapsed(d); actual != expected {
+			t.Fatalf(`expected %v to be formatted as %q; got %q`, d, expected, actual)
+		}
+	}
+	expect(0, `0s`)
+	expect(1234*time.Millisecond, `1.2s`)
+	expect(90*time.Second+400*time.Millisecond, `1m30s`)
+}
@@ -32,13 +32,25 @@ type Widget struct {
 // Layout does NOT satisfy the gocui.Manager interface, but contains
 // the common logic for other widget types that do.
 func (w *Widget) Layout(viewName string, g *gocui.Gui, customize func(*gocui.View)) error {
+	v, _, err := w.setView(viewName, g, customize)
+	if err != nil {
+		return err
+	}
+	v.Clear()
+	fmt.Fprintf(v, ` %s`, w.Stringer)
+	return w.setOrigin(v)
+}
+
+// setView places the widget's view, creating it if it doesn't
+// exist yet, and reports whether it had to.
+func (w *Widget) setView(viewName string, g *gocui.Gui, customize func(*gocui.View)) (*gocui.View, bool, error) {
 	v, err := g.SetView(
 		viewName,
 		w.X, w.Y,
 		w.X+w.W, w.Y+w.H,
 	)
 	if err != nil && err != gocui.ErrUnknownView {
-		return fmt.Errorf(`cannot get view for %q (%q): %w`, w.Title, viewName, err)
+		return nil, false, fmt.Errorf(`cannot get view for %q (%q): %w`, w.Title, viewName, err)
 	}
 	if w.Focus {
 		v.Title = fmt.Sprintf(`[%s]`, w.Title)
@@ -46,12 +58,14 @@ func (w *Widget) Layout(viewName string, g *gocui.Gui, customize func(*gocui.Vie
 		v.Title = fmt.Sprintf(` %s `, w.Title)
 	}
 	customize(v)
-	v.Clear()
-	fmt.Fprintf(v, ` %s`, w.Stringer)
 	v.Frame = true
+	return v, err == gocui.ErrUnknownView, nil
+}
+
+// setOrigin scrolls the view to the widget's scroll position.
+func (w *Widget) setOrigin(v *gocui.View) error {
 	ox, _ := v.Origin()
-	err = v.SetOrigin(ox, w.OriginY)
-	if err != nil {
+	if err := v.SetOrigin(ox, w.OriginY); err != nil {
 		return fmt.Errorf(`couldn't set origin of %q: %w`, w.Title, err)
 	}
 	return nil
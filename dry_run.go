@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/Unquabain/fac/task"
+	"github.com/Unquabain/fac/util"
+)
+
+// dryRun prints the plan for running the tasks, wave by wave,
+// without running any of them.
+func dryRun(list task.TaskList, failures []string) {
+	failed := make(map[string]bool, len(failures))
+	for _, name := range failures {
+		if _, ok := list[name]; !ok {
+			log.Printf(`Never heard of it. task not found: %q`, name)
+			os.Exit(-3)
+		}
+		failed[name] = true
+	}
+	waves, skipped, err := list.Plan(failed)
+	if err != nil {
+		log.Printf(`Can't see the future. %v`, err)
+		os.Exit(-5)
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		log.Printf(`Lost. %v`, err)
+		os.Exit(-5)
+	}
+	for i, wave := range waves {
+		fmt.Printf("Wave %d:\n", i+1)
+		for _, t := range wave {
+			outcome := ``
+			if failed[t.Name] {
+				outcome = ` (assumed to fail)`
+			}
+			fmt.Printf("  %s%s\n", t.Name, outcome)
+			fmt.Printf("    command:     %s\n", util.ShellQuote(t.CommandLine()))
+			if t.WorkingDirectory != `` {
+				fmt.Printf("    directory:   %s\n", t.WorkingDirectory)
+			} else {
+				fmt.Printf("    directory:   %s\n", dir)
+			}
+			if len(t.Environment) > 0 {
+				keys := make([]string, 0, len(t.Environment))
+				for key := range t.Environment {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				fmt.Printf("    environment:\n")
+				for _, key := range keys {
+					fmt.Printf("      %s=%s\n", key, t.Environment[key])
+				}
+			}
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Println(`Would not run:`)
+		for _, t := range skipped {
+			fmt.Printf("  %s\n", t.Name)
+		}
+	}
+}
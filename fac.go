@@ -1,31 +1,116 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/Unquabain/fac/display"
+	"github.com/Unquabain/fac/report"
 	"github.com/Unquabain/fac/task"
 	"github.com/jroimartin/gocui"
 	yaml "gopkg.in/yaml.v2"
 )
 
+var (
+	noTUI         = flag.Bool(`no-tui`, false, `Print task output line by line instead of using the text UI. (Default when STDOUT is not a terminal.)`)
+	jobs          = flag.Int(`j`, 0, `The most tasks to run at the same time. Overrides the "jobs" setting in the task file. (Default: no limit)`)
+	resultsFile   = flag.String(`results`, ``, `After the run, write how each task turned out to this file`)
+	dryRunPlan    = flag.Bool(`dry-run`, false, `Print the order the tasks would run in, without running them`)
+	failures      stringList
+	vars          stringList
+	allowExpected = flag.Bool(`allow-expected-failures`, false, `Exit successfully even if tasks failed, as long as other tasks depend on them failing ("!"), and tasks didn't run, as long as that was because of such a branch`)
+	quitWhenDone  = flag.Bool(`quit-when-done`, false, `Close the text UI as soon as all the tasks are finished`)
+	force         = flag.Bool(`force`, false, `Run tasks with "sources" or "generates" even if nothing has changed since they last succeeded`)
+	resume        = flag.Bool(`resume`, false, `Pick up where the last run left off: only run the tasks that didn't succeed in it`)
+	reportJSON    = flag.String(`report-json`, ``, `After the run, write a JSON report of how each task turned out to this file`)
+	reportJUnit   = flag.String(`report-junit`, ``, `After the run, write a JUnit XML report, with a test case for each task, to this file`)
+	reportOutput  = flag.Int(`report-max-output`, 64*1024, `The most bytes of each task's STDOUT and STDERR to include in reports; the rest is cut from the start (0: all of it)`)
+	spillOutput   = flag.Bool(`spill-output`, false, `Write the output that tasks drop from memory to stay under their "outputLimit" to files in .fac/output`)
+)
+
+// stateDir is the directory, next to the task file, where fac
+// keeps track of things between runs.
+const stateDir = `.fac`
+
+// journalFile is the name of the run journal in the stateDir.
+const journalFile = `journal.jsonl`
+
+// outputDir is the directory in the stateDir that output is
+// spilled to with --spill-output.
+const outputDir = `output`
+
+// tickInterval is how often the text UI is redrawn while nothing
+// else changes, to keep elapsed times current.
+const tickInterval = 500 * time.Millisecond
+
+// stringList is a flag that can be given more than once.
+type stringList []string
+
+func (sl *stringList) String() string {
+	return strings.Join(*sl, `, `)
+}
+
+func (sl *stringList) Set(value string) error {
+	*sl = append(*sl, value)
+	return nil
+}
+
+func init() {
+	flag.Var(&failures, `fail`, `With -dry-run, assume this task fails. May be given more than once.`)
+	flag.Var(&vars, `v`, varsUsage)
+}
+
+const varsUsage = `Set a variable for the task file, as NAME=value. Overrides the task file's "vars" and the environment. May be given more than once.`
+
+// overrides are the variables given with -v.
+func overrides() map[string]string {
+	values := make(map[string]string, len(vars))
+	for _, v := range vars {
+		i := strings.IndexByte(v, '=')
+		if i < 1 {
+			log.Printf(`That's not a variable. expected NAME=value: %q`, v)
+			printUsage()
+			os.Exit(-1)
+		}
+		values[v[:i]] = v[i+1:]
+	}
+	return values
+}
+
 func printUsage() {
-	fmt.Printf("Usage: %s taskfile.yaml\n", os.Args[0])
+	fmt.Printf("Usage: %s [options] taskfile.yaml [task ...]\n", os.Args[0])
+	fmt.Printf("       %s validate [-v NAME=value ...] taskfile.yaml\n", os.Args[0])
+	fmt.Printf("       %s graph [-format dot|mermaid] [-results results.yaml] [-v NAME=value ...] taskfile.yaml\n", os.Args[0])
+	fmt.Printf("       %s critical-path [-v NAME=value ...] taskfile.yaml\n", os.Args[0])
+	fmt.Println(``)
+	fmt.Println(`Commands:`)
+	fmt.Println(`  validate      Check the task file for problems without running anything`)
+	fmt.Println(`  graph         Print the task graph as Graphviz DOT or a Mermaid flowchart`)
+	fmt.Println(`  critical-path Print the longest chain of tasks, and the order tasks start in, using how long they took last run`)
 	fmt.Println(``)
 	fmt.Println(`Options:`)
 	fmt.Println(`  taskfile.yaml A YAML file with the things to do`)
+	fmt.Println(`  task          Only run these tasks, and the tasks they depend on`)
+	flag.CommandLine.SetOutput(os.Stdout)
+	flag.PrintDefaults()
 	fmt.Println(``)
 	fmt.Println(`Example Taskfile:`)
 	fmt.Println(`---
 Clear Logs:
-  command: zsh
-  args:
-    - rm
-    - logs/development.txt
-    - logs/test.txt
+  run: |
+    rm logs/development.txt
+    rm logs/test.txt
   expectedReturnCode: 7
 Update Bundler:
   command: bin/bundle
@@ -41,25 +126,209 @@ Update Bundler:
 	fmt.Println(`(expectedStdErrRegex is supported as well)`)
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		printUsage()
-		os.Exit(-1)
+// isTerminal reports whether f is attached to a terminal rather
+// than a pipe or a file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
 	}
-	yamlFile := os.Args[1]
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// loadTaskFile reads and parses the task file, or exits with an
+// explanation if it can't. It returns the source text, too.
+func loadTaskFile(yamlFile string) (*task.TaskFile, []byte) {
 	buff, err := ioutil.ReadFile(yamlFile)
 	if err != nil {
 		log.Printf(`No can do, Compadre. %v`, err)
 		printUsage()
 		os.Exit(-2)
 	}
-	list := make(task.TaskList)
-	err = yaml.Unmarshal(buff, &list)
+	file := &task.TaskFile{Overrides: overrides()}
+	err = yaml.Unmarshal(buff, file)
 	if err != nil {
 		log.Printf(`No love here. %v`, err)
 		printUsage()
 		os.Exit(-3)
 	}
+	file.Tasks.ResolveWorkingDirectories(filepath.Dir(yamlFile))
+	return file, buff
+}
+
+// printProblems validates the TaskList, printing any problems it
+// finds along with where they are in the task file. It returns
+// true if there were any.
+func printProblems(yamlFile string, source []byte, list task.TaskList) bool {
+	problems := list.Validate()
+	problems.Locate(source)
+	for _, problem := range problems {
+		fmt.Printf("%s:%d: %v\n", yamlFile, problem.Line, problem)
+	}
+	return len(problems) > 0
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case `validate`:
+			validate(os.Args[2:])
+			return
+		case `graph`:
+			graph(os.Args[2:])
+			return
+		case `critical-path`:
+			criticalPath(os.Args[2:])
+			return
+		}
+	}
+
+	flag.Usage = printUsage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		printUsage()
+		os.Exit(-1)
+	}
+	yamlFile := flag.Arg(0)
+	file, source := loadTaskFile(yamlFile)
+	if printProblems(yamlFile, source, file.Tasks) {
+		os.Exit(-3)
+	}
+	list := file.Tasks
+	var err error
+	if targets := flag.Args()[1:]; len(targets) > 0 {
+		list, err = list.Subset(targets...)
+		if err != nil {
+			log.Printf(`Never heard of it. %v`, err)
+			os.Exit(-3)
+		}
+	}
+	opts := file.RunOptions()
+	if *jobs > 0 {
+		opts.Jobs = *jobs
+	}
+	opts.StateDir = filepath.Join(filepath.Dir(yamlFile), stateDir)
+	opts.Force = *force
+	if *spillOutput {
+		opts.OutputDir = filepath.Join(opts.StateDir, outputDir)
+	}
+	journalPath := filepath.Join(opts.StateDir, journalFile)
+	// How long the tasks took last time decides which go first.
+	// Read it before it's replaced by this run's.
+	history, err := task.ReadJournal(journalPath)
+	if *resume {
+		if err != nil {
+			log.Printf(`Nothing to pick up. %v`, err)
+			os.Exit(-2)
+		}
+		log.Printf(`Picking up where the last run left off: %d tasks already succeeded`, list.Resume(history))
+	}
+	opts.History = history
+	if *dryRunPlan {
+		dryRun(list, failures)
+		return
+	}
+	journal, err := task.OpenJournal(journalPath, list)
+	if err != nil {
+		log.Printf(`Dear diary... %v`, err)
+		os.Exit(-2)
+	}
+	if *noTUI || !isTerminal(os.Stdout) {
+		runHeadless(list, opts, journal)
+	} else {
+		runTUI(list, opts, journal)
+	}
+	if err := journal.Close(); err != nil {
+		log.Printf(`Dear diary... %v`, err)
+	}
+	os.Exit(exitStatus(list))
+}
+
+// exitStatus is the status for fac to exit with once the tasks
+// have run: 0 if they all succeeded (or failed as planned, with
+// -allow-expected-failures), and 1 if any didn't.
+func exitStatus(list task.TaskList) int {
+	failed := list.Failures(*allowExpected)
+	if len(failed) == 0 {
+		return 0
+	}
+	names := make([]string, len(failed))
+	for i, t := range failed {
+		names[i] = fmt.Sprintf(`%q (%s)`, t.Name, t.GetStatus())
+		if failures := t.GetFailures(); len(failures) > 0 {
+			names[i] = fmt.Sprintf(`%q (%s: %s)`, t.Name, t.GetStatus(), failures[0])
+		}
+	}
+	log.Printf(`Not everything went to plan: %s`, strings.Join(names, `, `))
+	return 1
+}
+
+// writeResults writes how each task turned out to the -results
+// file, if there is one.
+func writeResults(list task.TaskList) {
+	if *resultsFile == `` {
+		return
+	}
+	if err := list.Records().Write(*resultsFile); err != nil {
+		log.Printf(`Couldn't keep track. %v`, err)
+	}
+}
+
+// writeReports writes the reports asked for on the command line.
+func writeReports(list task.TaskList) {
+	opts := report.Options{MaxOutput: *reportOutput, AllowExpectedFailures: *allowExpected}
+	if *reportJSON != `` {
+		if err := report.WriteJSON(*reportJSON, list, opts); err != nil {
+			log.Printf(`Couldn't report back. %v`, err)
+		}
+	}
+	if *reportJUnit != `` {
+		// The test suite is named after the task file.
+		name := filepath.Base(flag.Arg(0))
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+		if err := report.WriteJUnit(*reportJUnit, name, list, opts); err != nil {
+			log.Printf(`Couldn't report back. %v`, err)
+		}
+	}
+}
+
+// runHeadless runs the tasks without the text UI, streaming their
+// output and status changes to STDOUT and STDERR. The first
+// interrupt stops the tasks gracefully; the second gives up on
+// them and exits right away.
+func runHeadless(list task.TaskList, opts task.RunOptions, journal *task.Journal) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 2)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupts)
+	go func() {
+		if _, ok := <-interrupts; !ok {
+			return
+		}
+		log.Printf(`Stopping the tasks. Interrupt again to quit right away.`)
+		cancel()
+		if _, ok := <-interrupts; !ok {
+			return
+		}
+		os.Exit(130)
+	}()
+
+	printer := display.NewLogPrinter(os.Stdout, os.Stderr)
+	err := list.RunAll(ctx, opts, journal.Handler(printer.Handle))
+	writeResults(list)
+	writeReports(list)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf(`Ouch!: %v`, err)
+		os.Exit(-5)
+	}
+}
+
+// runTUI runs the tasks while displaying their progress in the
+// text UI. The first Ctrl-C stops the tasks gracefully, and closes
+// the UI once they have. The second (or the first, once the tasks
+// are done) closes it right away.
+func runTUI(list task.TaskList, opts task.RunOptions, journal *task.Journal) {
 	manager := &display.TaskLayoutManager{TaskList: list}
 
 	g, err := gocui.NewGui(gocui.Output256)
@@ -70,17 +339,88 @@ func main() {
 	defer g.Close()
 	g.SetManager(manager)
 
-	handler := func(s *task.Task) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	// Tasks reset while nothing is running are run by running
+	// them all again.
+	rerun := make(chan struct{}, 1)
+	// The screen is redrawn every tickInterval while something
+	// moves on between the tasks' updates: elapsed times while
+	// tasks are running, and the timeline. Once the tasks are
+	// done, the ticker is stopped until they're run again or
+	// the timeline is shown.
+	wake := make(chan struct{}, 1)
+	nudge := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+	var showingTimeline int32
+	manager.OnToggleTimeline = func(shown bool) {
+		if shown {
+			atomic.StoreInt32(&showingTimeline, 1)
+			nudge()
+		} else {
+			atomic.StoreInt32(&showingTimeline, 0)
+		}
+	}
+	manager.OnRerun = func() {
+		select {
+		case rerun <- struct{}{}:
+		default:
+		}
+		nudge()
+	}
+	waitForRerun := func() bool {
+		select {
+		case <-rerun:
+			return ctx.Err() == nil
+		case <-ctx.Done():
+			return false
+		}
+	}
+	handler := journal.Handler(func(s *task.Task) {
 		g.Update(func(gg *gocui.Gui) error {
 			return manager.Layout(gg)
 		})
-	}
+	})
 	go func() {
-		err := list.RunAll(handler)
-		if err != nil {
-			log.Printf(`Ouch!: %v`, err)
-			printUsage()
-			os.Exit(-5)
+		defer close(done)
+		for {
+			err := list.RunAll(ctx, opts, handler)
+			writeResults(list)
+			writeReports(list)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf(`Ouch!: %v`, err)
+				printUsage()
+				os.Exit(-5)
+			}
+			if *quitWhenDone || !waitForRerun() {
+				break
+			}
+		}
+		g.Update(func(_ *gocui.Gui) error { return gocui.ErrQuit })
+	}()
+
+	stopTicking := make(chan struct{})
+	defer close(stopTicking)
+	go func() {
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				manager.Update(g)
+				if list.IsFinished() && atomic.LoadInt32(&showingTimeline) == 0 {
+					ticker.Stop()
+				}
+			case <-wake:
+				ticker.Reset(tickInterval)
+			case <-stopTicking:
+				return
+			}
 		}
 	}()
 
@@ -88,7 +428,18 @@ func main() {
 		"",
 		gocui.KeyCtrlC,
 		gocui.ModNone,
-		func(_ *gocui.Gui, _ *gocui.View) error { return gocui.ErrQuit },
+		func(_ *gocui.Gui, _ *gocui.View) error {
+			select {
+			case <-done:
+				return gocui.ErrQuit
+			default:
+			}
+			if ctx.Err() != nil {
+				return gocui.ErrQuit
+			}
+			cancel()
+			return nil
+		},
 	)
 	if err != nil {
 		log.Printf(`No keybindings for you: %v`, err)
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/Unquabain/fac/task"
+)
+
+// graph is the "graph" command. It prints the task graph as
+// Graphviz DOT or a Mermaid flowchart.
+func graph(args []string) {
+	flags := flag.NewFlagSet(`graph`, flag.ExitOnError)
+	flags.Usage = printUsage
+	format := flags.String(`format`, `dot`, `The graph format: "dot" or "mermaid"`)
+	resultsFile := flags.String(`results`, ``, `Color the tasks by how they turned out in the run that wrote this results file`)
+	flags.Var(&vars, `v`, varsUsage)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		printUsage()
+		os.Exit(-1)
+	}
+	file, _ := loadTaskFile(flags.Arg(0))
+
+	records := make(task.Records)
+	if *resultsFile != `` {
+		var err error
+		records, err = task.ReadRecords(*resultsFile)
+		if err != nil {
+			log.Printf(`Can't remember. %v`, err)
+			os.Exit(-2)
+		}
+	}
+
+	var err error
+	switch *format {
+	case `dot`:
+		err = file.Tasks.WriteDOT(os.Stdout, records)
+	case `mermaid`:
+		err = file.Tasks.WriteMermaid(os.Stdout, records)
+	default:
+		log.Printf(`I don't draw %q.`, *format)
+		printUsage()
+		os.Exit(-1)
+	}
+	if err != nil {
+		log.Printf(`Couldn't draw it. %v`, err)
+		os.Exit(-8)
+	}
+}
@@ -0,0 +1,136 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/Unquabain/fac/task"
+)
+
+// JSONVersion is the version of the JSON report's schema. It goes
+// up whenever a field is removed or changes meaning, but not when
+// one is added.
+const JSONVersion = 1
+
+// JSONReport is the JSON report of a run. Its schema is
+// documented in the README.
+type JSONReport struct {
+	Version  int        `json:"version"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Summary  Summary    `json:"summary"`
+	Tasks    []JSONTask `json:"tasks"`
+}
+
+// Summary is how the run went overall.
+type Summary struct {
+	// OK is whether fac considers the run a success: whether
+	// it exits with 0.
+	OK bool `json:"ok"`
+
+	Total int `json:"total"`
+
+	// Statuses counts the Tasks that ended with each Status.
+	Statuses map[string]int `json:"statuses"`
+
+	// Failures are the names of the Tasks that kept the run
+	// from being OK.
+	Failures []string `json:"failures"`
+
+	// DurationSeconds is how long it was from the first Task
+	// starting to the last one finishing.
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// JSONTask is how one Task went.
+type JSONTask struct {
+	Name            string     `json:"name"`
+	Command         string     `json:"command"`
+	Args            []string   `json:"args"`
+	Status          string     `json:"status"`
+	ReturnCode      int        `json:"returnCode"`
+	Started         *time.Time `json:"started,omitempty"`
+	Finished        *time.Time `json:"finished,omitempty"`
+	DurationSeconds float64    `json:"durationSeconds"`
+	Attempts        int        `json:"attempts"`
+
+	// Failures are why the Task failed, if it did.
+	Failures []task.Failure `json:"failures"`
+
+	StdOut          string `json:"stdout"`
+	StdOutTruncated bool   `json:"stdoutTruncated,omitempty"`
+	StdErr          string `json:"stderr"`
+	StdErrTruncated bool   `json:"stderrTruncated,omitempty"`
+}
+
+// timestamp is nil for a zero time, so that it's left out.
+func timestamp(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// NewJSONReport reports on the TaskList as it stands.
+func NewJSONReport(list task.TaskList, opts Options) *JSONReport {
+	report := &JSONReport{
+		Version: JSONVersion,
+		Summary: Summary{
+			OK:       true,
+			Total:    len(list),
+			Statuses: make(map[string]int),
+			Failures: []string{},
+		},
+		Tasks: make([]JSONTask, 0, len(list)),
+	}
+	for _, t := range sorted(list) {
+		record := t.Record()
+		// What actually ran, so a run script shows up as its
+		// shell and the script.
+		commandLine := t.CommandLine()
+		jt := JSONTask{
+			Name:            t.Name,
+			Command:         commandLine[0],
+			Args:            commandLine[1:],
+			Status:          record.Status.String(),
+			ReturnCode:      record.ReturnCode,
+			Started:         timestamp(record.Started),
+			Finished:        timestamp(record.Finished),
+			DurationSeconds: record.Duration().Seconds(),
+			Attempts:        record.Attempts,
+			Failures:        record.Failures,
+		}
+		if jt.Failures == nil {
+			jt.Failures = []task.Failure{}
+		}
+		jt.StdOut, jt.StdOutTruncated = truncate(record.StdOut, opts.MaxOutput)
+		jt.StdErr, jt.StdErrTruncated = truncate(record.StdErr, opts.MaxOutput)
+		report.Tasks = append(report.Tasks, jt)
+		report.Summary.Statuses[jt.Status]++
+	}
+	for _, t := range list.Failures(opts.AllowExpectedFailures) {
+		report.Summary.OK = false
+		report.Summary.Failures = append(report.Summary.Failures, t.Name)
+	}
+	started, finished := span(list)
+	report.Started, report.Finished = timestamp(started), timestamp(finished)
+	if !started.IsZero() && finished.After(started) {
+		report.Summary.DurationSeconds = finished.Sub(started).Seconds()
+	}
+	return report
+}
+
+// WriteJSON writes the JSON report of the TaskList to a file.
+func WriteJSON(path string, list task.TaskList, opts Options) error {
+	report := NewJSONReport(list, opts)
+	buff, err := json.MarshalIndent(report, ``, `  `)
+	if err != nil {
+		return fmt.Errorf(`couldn't serialize JSON report: %w`, err)
+	}
+	if err := ioutil.WriteFile(path, append(buff, '\n'), 0644); err != nil {
+		return fmt.Errorf(`couldn't write JSON report: %w`, err)
+	}
+	return nil
+}
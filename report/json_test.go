@@ -0,0 +1,97 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/Unquabain/fac/task"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var reportYAML = `---
+Build:
+  command: echo
+  args: [built]
+Test:
+  run: echo testing; exit 3
+  dependencies: [Build]
+Deploy:
+  command: echo
+  dependencies: [Test]
+`
+
+func runReportYAML(t *testing.T) task.TaskList {
+	t.Helper()
+	return runYAML(t, reportYAML)
+}
+
+func runYAML(t *testing.T, source string) task.TaskList {
+	t.Helper()
+	list := make(task.TaskList)
+	if err := yaml.Unmarshal([]byte(source), &list); err != nil {
+		t.Fatalf(`could not create example TaskList: %v`, err)
+	}
+	if err := list.RunAll(context.Background(), task.RunOptions{}, func(*task.Task) {}); err != nil {
+		t.Fatalf(`could not run example TaskList: %v`, err)
+	}
+	return list
+}
+
+func TestWriteJSON(t *testing.T) {
+	list := runReportYAML(t)
+	path := filepath.Join(t.TempDir(), `report.json`)
+	if err := WriteJSON(path, list, Options{MaxOutput: 4}); err != nil {
+		t.Fatalf(`unexpected error writing report: %v`, err)
+	}
+	buff, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf(`could not read report: %v`, err)
+	}
+	var report JSONReport
+	if err := json.Unmarshal(buff, &report); err != nil {
+		t.Fatalf(`could not parse report: %v`, err)
+	}
+	if actual := report.Version; actual != JSONVersion {
+		t.Fatalf(`expected version %d; got %d`, JSONVersion, actual)
+	}
+	if actual := len(report.Tasks); actual != 3 {
+		t.Fatalf(`expected 3 tasks; got %d`, actual)
+	}
+	build, deploy, test := report.Tasks[0], report.Tasks[1], report.Tasks[2]
+	if build.Name != `Build` || build.Command != `echo` || len(build.Args) != 1 || build.Status != `Succeeded` {
+		t.Fatalf(`unexpected report for Build: %+v`, build)
+	}
+	if build.StdOut != "ilt\n" || !build.StdOutTruncated {
+		t.Fatalf(`expected Build's output to be cut to its last 4 bytes; got %q`, build.StdOut)
+	}
+	if build.Started == nil || build.Finished == nil || build.Attempts != 1 {
+		t.Fatalf(`expected Build to have been timed; got %+v`, build)
+	}
+	if test.Status != `Failed` || test.ReturnCode != 3 || test.Command != `/bin/sh` {
+		t.Fatalf(`unexpected report for Test: %+v`, test)
+	}
+	if deploy.Status != `Dependencies Not Met` || deploy.Started != nil || deploy.Attempts != 0 {
+		t.Fatalf(`unexpected report for Deploy: %+v`, deploy)
+	}
+	summary := report.Summary
+	if summary.OK || summary.Total != 3 || summary.Statuses[`Succeeded`] != 1 || len(summary.Failures) != 2 {
+		t.Fatalf(`unexpected summary: %+v`, summary)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	expect := func(text string, max int, expected string, truncated bool) {
+		t.Helper()
+		actual, cut := truncate(text, max)
+		if actual != expected || cut != truncated {
+			t.Fatalf(`expected truncate(%q, %d) to be %q, %v; was %q, %v`, text, max, expected, truncated, actual, cut)
+		}
+	}
+	expect(`hello`, 0, `hello`, false)
+	expect(`hello`, 5, `hello`, false)
+	expect(`hello`, 3, `llo`, true)
+	expect(`a€b`, 3, `b`, true)
+}
@@ -0,0 +1,161 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/Unquabain/fac/task"
+)
+
+// The JUnit XML format, as far as CI systems like Jenkins and
+// GitLab read it.
+type (
+	junitTestSuites struct {
+		XMLName  xml.Name         `xml:"testsuites"`
+		Name     string           `xml:"name,attr"`
+		Tests    int              `xml:"tests,attr"`
+		Failures int              `xml:"failures,attr"`
+		Skipped  int              `xml:"skipped,attr"`
+		Time     string           `xml:"time,attr"`
+		Suites   []junitTestSuite `xml:"testsuite"`
+	}
+
+	junitTestSuite struct {
+		Name      string          `xml:"name,attr"`
+		Tests     int             `xml:"tests,attr"`
+		Failures  int             `xml:"failures,attr"`
+		Skipped   int             `xml:"skipped,attr"`
+		Time      string          `xml:"time,attr"`
+		Timestamp string          `xml:"timestamp,attr,omitempty"`
+		Cases     []junitTestCase `xml:"testcase"`
+	}
+
+	junitTestCase struct {
+		Name      string        `xml:"name,attr"`
+		ClassName string        `xml:"classname,attr"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitFailure `xml:"failure,omitempty"`
+		Skipped   *junitSkipped `xml:"skipped,omitempty"`
+		SystemOut string        `xml:"system-out,omitempty"`
+		SystemErr string        `xml:"system-err,omitempty"`
+	}
+
+	junitFailure struct {
+		Message string `xml:"message,attr"`
+		Type    string `xml:"type,attr"`
+		Text    string `xml:",chardata"`
+	}
+
+	junitSkipped struct {
+		Message string `xml:"message,attr"`
+	}
+)
+
+// seconds formats a duration the way JUnit does.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf(`%.3f`, d.Seconds())
+}
+
+// junitFailureOf describes why the Task failed, or returns nil if
+// it didn't.
+func junitFailureOf(t *task.Task, record task.Record) *junitFailure {
+	if !record.Status.IsFailure() {
+		return nil
+	}
+	messages := make([]string, len(record.Failures))
+	for i, failure := range record.Failures {
+		messages[i] = failure.Message
+	}
+	message := strings.Join(messages, `; `)
+	if message == `` {
+		message = strings.ToLower(record.Status.String())
+	}
+	text := new(strings.Builder)
+	fmt.Fprintf(text, "%s\nattempts: %d\n", strings.Join(t.CommandLine(), ` `), record.Attempts)
+	return &junitFailure{Message: message, Type: record.Status.String(), Text: text.String()}
+}
+
+// junitSkippedOf says why the Task didn't run, or returns nil if
+// it did.
+func junitSkippedOf(record task.Record) *junitSkipped {
+	switch record.Status {
+	case task.StatusDependenciesNotMet:
+		return &junitSkipped{Message: `dependencies not met`}
+	case task.StatusCancelled:
+		return &junitSkipped{Message: `cancelled`}
+	case task.StatusNotRun, task.StatusQueued, task.StatusRunning:
+		return &junitSkipped{Message: `didn't finish running`}
+	}
+	return nil
+}
+
+// newJUnit reports on the TaskList as a single test suite with the
+// given name, with a test case for each Task.
+//
+// With opts.AllowExpectedFailures, the failures that the task file
+// plans for are skipped rather than failed, so that a run fac
+// exits successfully from doesn't fail in CI.
+func newJUnit(name string, list task.TaskList, opts Options) *junitTestSuites {
+	counted := make(map[string]bool)
+	for _, t := range list.Failures(opts.AllowExpectedFailures) {
+		counted[t.Name] = true
+	}
+	suite := junitTestSuite{Name: name, Cases: make([]junitTestCase, 0, len(list))}
+	for _, t := range sorted(list) {
+		record := t.Record()
+		tc := junitTestCase{
+			Name:      t.Name,
+			ClassName: name,
+			Time:      seconds(record.Duration()),
+			Failure:   junitFailureOf(t, record),
+			Skipped:   junitSkippedOf(record),
+		}
+		if tc.Failure != nil && !counted[t.Name] {
+			tc.Skipped = &junitSkipped{Message: `failed as planned: ` + tc.Failure.Message}
+			tc.Failure = nil
+		}
+		tc.SystemOut, _ = truncate(record.StdOut, opts.MaxOutput)
+		tc.SystemErr, _ = truncate(record.StdErr, opts.MaxOutput)
+		if tc.Failure != nil {
+			suite.Failures++
+		}
+		if tc.Skipped != nil {
+			suite.Skipped++
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	suite.Time = seconds(0)
+	if started, finished := span(list); !started.IsZero() {
+		suite.Timestamp = started.Format(`2006-01-02T15:04:05`)
+		if finished.After(started) {
+			suite.Time = seconds(finished.Sub(started))
+		}
+	}
+	return &junitTestSuites{
+		Name:     name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+}
+
+// WriteJUnit writes a JUnit XML report of the TaskList to a file,
+// as a test suite with the given name (like the task file's) and a
+// test case for each Task.
+func WriteJUnit(path, name string, list task.TaskList, opts Options) error {
+	buff, err := xml.MarshalIndent(newJUnit(name, list, opts), ``, `  `)
+	if err != nil {
+		return fmt.Errorf(`couldn't serialize JUnit report: %w`, err)
+	}
+	buff = append([]byte(xml.Header), append(buff, '\n')...)
+	if err := ioutil.WriteFile(path, buff, 0644); err != nil {
+		return fmt.Errorf(`couldn't write JUnit report: %w`, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package report
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	list := runReportYAML(t)
+	path := filepath.Join(t.TempDir(), `junit.xml`)
+	if err := WriteJUnit(path, `facenda`, list, Options{}); err != nil {
+		t.Fatalf(`unexpected error writing report: %v`, err)
+	}
+	buff, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf(`could not read report: %v`, err)
+	}
+	if !strings.HasPrefix(string(buff), `<?xml`) {
+		t.Fatalf(`expected an XML header; report starts %q`, buff[:10])
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buff, &suites); err != nil {
+		t.Fatalf(`could not parse report: %v`, err)
+	}
+	if suites.Tests != 3 || suites.Failures != 1 || suites.Skipped != 1 || len(suites.Suites) != 1 {
+		t.Fatalf(`unexpected totals: %+v`, suites)
+	}
+	cases := suites.Suites[0].Cases
+	build, deploy, test := cases[0], cases[1], cases[2]
+	if build.Name != `Build` || build.ClassName != `facenda` || build.Failure != nil || build.SystemOut != "built\n" {
+		t.Fatalf(`unexpected test case for Build: %+v`, build)
+	}
+	if test.Failure == nil || test.Failure.Message != `return code was 3, expected 0` {
+		t.Fatalf(`expected Test to fail with its return code; got %+v`, test.Failure)
+	}
+	if deploy.Skipped == nil || deploy.Failure != nil {
+		t.Fatalf(`expected Deploy to be skipped; got %+v`, deploy)
+	}
+}
+
+func TestJUnitExpectedFailures(t *testing.T) {
+	list := runYAML(t, `---
+Load DB Dump:
+  run: exit 1
+Migrate DB:
+  command: "true"
+  dependencies: ["! Load DB Dump"]
+`)
+	suites := newJUnit(`facenda`, list, Options{})
+	if suites.Failures != 1 || suites.Skipped != 0 {
+		t.Fatalf(`expected the planned failure to fail without --allow-expected-failures; got %+v`, suites)
+	}
+	suites = newJUnit(`facenda`, list, Options{AllowExpectedFailures: true})
+	if suites.Failures != 0 || suites.Skipped != 1 {
+		t.Fatalf(`expected the planned failure to be skipped; got %+v`, suites)
+	}
+	load := suites.Suites[0].Cases[0]
+	if load.Skipped == nil || load.Skipped.Message != `failed as planned: return code was 1, expected 0` {
+		t.Fatalf(`expected Load DB Dump to be skipped as planned; got %+v`, load)
+	}
+}
@@ -0,0 +1,64 @@
+// Package report writes what happened in a run, once it's over, in
+// formats that other tools can read.
+package report
+
+import (
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Unquabain/fac/task"
+)
+
+// Options tune what goes into a report.
+type Options struct {
+	// MaxOutput is how many bytes of each Task's STDOUT and
+	// STDERR are included. Longer output is cut down to its
+	// last MaxOutput bytes. Zero means all of it.
+	MaxOutput int
+
+	// AllowExpectedFailures leaves the failures that the task
+	// file plans for out of the summary's failures, like the
+	// --allow-expected-failures option.
+	AllowExpectedFailures bool
+}
+
+// sorted returns the Tasks by name, so that reports of the same
+// task file always list them in the same order.
+func sorted(list task.TaskList) []*task.Task {
+	tasks := make([]*task.Task, 0, len(list))
+	for _, t := range list {
+		tasks = append(tasks, t)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Name < tasks[j].Name
+	})
+	return tasks
+}
+
+// span is when the first Task started running and the last one
+// finished, or zero times if none of them ran.
+func span(list task.TaskList) (started, finished time.Time) {
+	for _, t := range list {
+		if s := t.GetStarted(); !s.IsZero() && (started.IsZero() || s.Before(started)) {
+			started = s
+		}
+		if f := t.GetFinished(); f.After(finished) {
+			finished = f
+		}
+	}
+	return started, finished
+}
+
+// truncate cuts text down to its last max bytes, without starting
+// in the middle of a character. It reports whether it cut anything.
+func truncate(text string, max int) (string, bool) {
+	if max <= 0 || len(text) <= max {
+		return text, false
+	}
+	start := len(text) - max
+	for start < len(text) && !utf8.RuneStart(text[start]) {
+		start++
+	}
+	return text[start:], true
+}
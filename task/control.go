@@ -0,0 +1,133 @@
+package task
+
+import "context"
+
+// control is how a running scheduler hears about Tasks that were
+// skipped or reset to be run again while it was running. Tasks that
+// were changed together are sent together.
+type control struct {
+	events  chan []*Task
+	stopped chan struct{}
+}
+
+func (s *Task) setControl(ctl *control) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.control = ctl
+}
+
+// notify lets the scheduler running the Tasks, if there is one,
+// know that their Statuses were changed from outside. If there
+// isn't one, the next run will pick up the change.
+func notify(tasks []*Task) {
+	s := tasks[0]
+	s.mtx.Lock()
+	ctl := s.control
+	s.mtx.Unlock()
+	if ctl == nil {
+		return
+	}
+	select {
+	case ctl.events <- tasks:
+	case <-ctl.stopped:
+	}
+}
+
+func (s *Task) setForced(forced bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.forced = forced
+}
+
+// takeForced reports whether the Task was reset to run again
+// since it was last launched, and clears it.
+func (s *Task) takeForced() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	forced := s.forced
+	s.forced = false
+	return forced
+}
+
+func (s *Task) setCancel(cancel context.CancelFunc) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.cancel = cancel
+}
+
+// Cancel stops the Task's command if it's running. The Task is
+// marked StatusCancelled, so the Tasks that depend on it won't
+// run. It reports whether the Task was running.
+func (s *Task) Cancel() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.cancel == nil {
+		return false
+	}
+	s.cancel()
+	return true
+}
+
+// Skip marks a Task that's waiting to run StatusCancelled, so that
+// it won't, and neither will the Tasks that depend on it. It
+// reports whether the Task was waiting.
+func (sl TaskList) Skip(task *Task) bool {
+	skipped := false
+	task.results.Atomic(func(r Results) {
+		if status := r.GetStatus(); status == StatusNotRun || status == StatusQueued {
+			r.SetStatus(StatusCancelled)
+			skipped = true
+		}
+	})
+	if skipped {
+		notify([]*Task{task})
+	}
+	return skipped
+}
+
+// Rerun resets a finished Task to StatusNotRun, clearing its
+// results, so that it runs again. So are the Tasks downstream of
+// it (that depend on it, directly or not) that didn't get to run
+// because of it. If downstream is set, all the finished Tasks
+// downstream of it are reset, even the ones that did run.
+//
+// If RunAll is running, it runs them as soon as their
+// dependencies allow, and the Tasks that depend on them and
+// haven't started yet, even queued ones, wait for them again.
+// If not, the next call to RunAll will. They run even if they're
+// up to date. It returns the Tasks that were reset, which is
+// none if the Task isn't finished.
+func (sl TaskList) Rerun(task *Task, downstream bool) []*Task {
+	if !task.GetStatus().IsFinal() {
+		return nil
+	}
+	dependents := make(map[string][]*Task, len(sl))
+	for _, t := range sl.sorted() {
+		for _, dep := range t.Dependencies {
+			key, _ := parseDependencyName(dep)
+			dependents[key] = append(dependents[key], t)
+		}
+	}
+	reset := []*Task{task}
+	seen := map[string]bool{task.Name: true}
+	for i := 0; i < len(reset); i++ {
+		for _, t := range dependents[reset[i].Name] {
+			status := t.GetStatus()
+			if seen[t.Name] || !status.IsFinal() {
+				continue
+			}
+			if downstream || status == StatusDependenciesNotMet {
+				seen[t.Name] = true
+				reset = append(reset, t)
+			}
+		}
+	}
+	// Reset them all before telling the scheduler about any, so
+	// that it sees which ones are waiting on which.
+	for _, t := range reset {
+		t.results.Reset()
+		t.setForced(true)
+	}
+	notify(reset)
+	return reset
+}
@@ -0,0 +1,263 @@
+package task
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRerun(t *testing.T) {
+	list := TaskList{
+		`Build`:  &Task{Name: `Build`, results: NewResultsProxy()},
+		`Test`:   &Task{Name: `Test`, Dependencies: []string{`Build`}, results: NewResultsProxy()},
+		`Deploy`: &Task{Name: `Deploy`, Dependencies: []string{`Test`}, results: NewResultsProxy()},
+		`Lint`:   &Task{Name: `Lint`, results: NewResultsProxy()},
+	}
+	outcomes := map[string]Status{`Build`: StatusFailed}
+	if err := list.runAll(context.Background(), RunOptions{}, func(*Task) {}, fakeRunner(outcomes)); err != nil {
+		t.Fatalf(`unexpected error running tasks: %v`, err)
+	}
+	expect := func(name string, expected Status) {
+		t.Helper()
+		if actual := list[name].GetStatus(); actual != expected {
+			t.Fatalf(`expected %s to be %v; was %v`, name, expected, actual)
+		}
+	}
+	expect(`Test`, StatusDependenciesNotMet)
+
+	if reset := list.Rerun(list[`Build`], false); len(reset) != 3 {
+		t.Fatalf(`expected Build and the two tasks that didn't run because of it to be reset; got %d`, len(reset))
+	}
+	expect(`Build`, StatusNotRun)
+	expect(`Deploy`, StatusNotRun)
+	expect(`Lint`, StatusSucceeded)
+
+	delete(outcomes, `Build`)
+	if err := list.runAll(context.Background(), RunOptions{}, func(*Task) {}, fakeRunner(outcomes)); err != nil {
+		t.Fatalf(`unexpected error running tasks again: %v`, err)
+	}
+	for name := range list {
+		expect(name, StatusSucceeded)
+	}
+
+	if reset := list.Rerun(list[`Build`], false); len(reset) != 1 {
+		t.Fatalf(`expected only Build to be reset when its dependents succeeded; got %d`, len(reset))
+	}
+	if reset := list.Rerun(list[`Build`], true); len(reset) != 0 {
+		t.Fatalf(`expected a task that isn't finished not to be reset; got %d`, len(reset))
+	}
+	list[`Build`].results.SetStatus(StatusSucceeded)
+	if reset := list.Rerun(list[`Build`], true); len(reset) != 3 {
+		t.Fatalf(`expected Build and everything downstream of it to be reset; got %d`, len(reset))
+	}
+}
+
+func TestRerunUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, `in.txt`), []byte(`one`), 0644); err != nil {
+		t.Fatalf(`could not set up test files: %v`, err)
+	}
+	list, err := getTaskListFromYaml(`---
+workingDirectory: ` + dir + `
+Build:
+  command: make
+  sources: [in.txt]
+`)
+	if err != nil {
+		t.Fatalf(`could not create example TaskList: %v`, err)
+	}
+	opts := RunOptions{StateDir: filepath.Join(dir, `.fac`)}
+	runs := 0
+	run := fakeRunner(nil)
+	counting := func(ctx context.Context, s *Task, handler func(*Task)) error {
+		runs++
+		return run(ctx, s, handler)
+	}
+	runAll := func(expected Status, expectedRuns int) {
+		t.Helper()
+		if err := list.runAll(context.Background(), opts, func(*Task) {}, counting); err != nil {
+			t.Fatalf(`unexpected error running tasks: %v`, err)
+		}
+		if actual := list[`Build`].GetStatus(); actual != expected {
+			t.Fatalf(`expected Build to be %v; was %v`, expected, actual)
+		}
+		if runs != expectedRuns {
+			t.Fatalf(`expected Build to have run %d times; ran %d`, expectedRuns, runs)
+		}
+	}
+	runAll(StatusSucceeded, 1)
+	list.Rerun(list[`Build`], false)
+	runAll(StatusSucceeded, 2)
+	list[`Build`].results.Reset()
+	runAll(StatusUpToDate, 2)
+	list.Rerun(list[`Build`], false)
+	runAll(StatusSucceeded, 3)
+}
+
+// blockingRunner runs like fakeRunner, but calls the hook before
+// running the named Task.
+func blockingRunner(outcomes map[string]Status, name string, hook func()) runner {
+	run := fakeRunner(outcomes)
+	var once sync.Once
+	return func(ctx context.Context, s *Task, handler func(*Task)) error {
+		if s.Name == name {
+			once.Do(hook)
+		}
+		return run(ctx, s, handler)
+	}
+}
+
+func TestRerunWhileRunning(t *testing.T) {
+	list := TaskList{
+		`Build`: &Task{Name: `Build`, results: NewResultsProxy()},
+		`Test`:  &Task{Name: `Test`, Dependencies: []string{`Build`}, results: NewResultsProxy()},
+		`Slow`:  &Task{Name: `Slow`, results: NewResultsProxy()},
+	}
+	var mtx sync.Mutex
+	outcomes := map[string]Status{`Build`: StatusFailed}
+	run := blockingRunner(outcomes, `Slow`, func() {
+		for list[`Test`].GetStatus() != StatusDependenciesNotMet {
+			time.Sleep(time.Millisecond)
+		}
+		mtx.Lock()
+		delete(outcomes, `Build`)
+		mtx.Unlock()
+		list.Rerun(list[`Build`], false)
+	})
+	locked := func(ctx context.Context, s *Task, handler func(*Task)) error {
+		if s.Name != `Slow` {
+			mtx.Lock()
+			defer mtx.Unlock()
+		}
+		return run(ctx, s, handler)
+	}
+	if err := list.runAll(context.Background(), RunOptions{Jobs: 2}, func(*Task) {}, locked); err != nil {
+		t.Fatalf(`unexpected error running tasks: %v`, err)
+	}
+	for name, task := range list {
+		if actual := task.GetStatus(); actual != StatusSucceeded {
+			t.Fatalf(`expected %s to have been run again and succeeded; was %v`, name, actual)
+		}
+	}
+}
+
+func TestRerunWithWaitingDependents(t *testing.T) {
+	list := TaskList{
+		`Fast`:    &Task{Name: `Fast`, results: NewResultsProxy()},
+		`Slow`:    &Task{Name: `Slow`, Resources: []string{`db`}, results: NewResultsProxy()},
+		`Deploy`:  &Task{Name: `Deploy`, Dependencies: []string{`Fast`, `Slow`}, results: NewResultsProxy()},
+		`Publish`: &Task{Name: `Publish`, Dependencies: []string{`Fast`}, Resources: []string{`db`}, results: NewResultsProxy()},
+	}
+	run := fakeRunner(nil)
+	var mtx sync.Mutex
+	fastRuns := 0
+	fastDone := make(chan struct{})
+	var problems []string
+	check := func(ok bool, problem string) {
+		if !ok {
+			mtx.Lock()
+			problems = append(problems, problem)
+			mtx.Unlock()
+		}
+	}
+	runner := func(ctx context.Context, s *Task, handler func(*Task)) error {
+		switch s.Name {
+		case `Fast`:
+			err := run(ctx, s, handler)
+			mtx.Lock()
+			fastRuns++
+			if fastRuns == 1 {
+				close(fastDone)
+			}
+			mtx.Unlock()
+			return err
+		case `Slow`:
+			// Publish is queued behind Slow for the db, and Deploy
+			// is waiting on Slow, when Fast is run again.
+			<-fastDone
+			for list[`Publish`].GetStatus() != StatusQueued {
+				time.Sleep(time.Millisecond)
+			}
+			list.Rerun(list[`Fast`], false)
+			time.Sleep(50 * time.Millisecond)
+		case `Deploy`:
+			check(list[`Slow`].GetStatus().IsFinal(), `Deploy started before Slow finished`)
+			check(list[`Fast`].GetStatus().IsFinal(), `Deploy started before Fast finished again`)
+		case `Publish`:
+			mtx.Lock()
+			runs := fastRuns
+			mtx.Unlock()
+			check(runs == 2, `Publish started before Fast finished again`)
+		}
+		return run(ctx, s, handler)
+	}
+	if err := list.runAll(context.Background(), RunOptions{}, func(*Task) {}, runner); err != nil {
+		t.Fatalf(`unexpected error running tasks: %v`, err)
+	}
+	if len(problems) > 0 {
+		t.Fatalf(`expected dependents to wait for the rerun task: %v`, problems)
+	}
+	for name, task := range list {
+		if actual := task.GetStatus(); actual != StatusSucceeded {
+			t.Fatalf(`expected %s to succeed; was %v`, name, actual)
+		}
+	}
+}
+
+func TestSkipWhileRunning(t *testing.T) {
+	list := TaskList{
+		`Build`:  &Task{Name: `Build`, results: NewResultsProxy()},
+		`Test`:   &Task{Name: `Test`, Dependencies: []string{`Build`}, results: NewResultsProxy()},
+		`Deploy`: &Task{Name: `Deploy`, Dependencies: []string{`Test`}, results: NewResultsProxy()},
+	}
+	skipped := false
+	run := blockingRunner(nil, `Build`, func() {
+		skipped = list.Skip(list[`Test`])
+	})
+	if err := list.runAll(context.Background(), RunOptions{}, func(*Task) {}, run); err != nil {
+		t.Fatalf(`unexpected error running tasks: %v`, err)
+	}
+	if !skipped {
+		t.Fatalf(`expected a task waiting on its dependencies to be skipped`)
+	}
+	expect := func(name string, expected Status) {
+		t.Helper()
+		if actual := list[name].GetStatus(); actual != expected {
+			t.Fatalf(`expected %s to be %v; was %v`, name, expected, actual)
+		}
+	}
+	expect(`Build`, StatusSucceeded)
+	expect(`Test`, StatusCancelled)
+	expect(`Deploy`, StatusDependenciesNotMet)
+	if list.Skip(list[`Build`]) {
+		t.Fatalf(`expected a finished task not to be skipped`)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	task := newSuccessfulTask()
+	task.Command = `sleep`
+	task.Args = []string{`5`}
+	if task.Cancel() {
+		t.Fatalf(`expected a task that isn't running not to be cancelled`)
+	}
+	go func() {
+		for task.GetStatus() != StatusRunning {
+			time.Sleep(time.Millisecond)
+		}
+		task.Cancel()
+	}()
+	start := time.Now()
+	if err := task.Run(context.Background(), func(*Task) {}); err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusCancelled {
+		t.Fatalf(`task should have been cancelled; wasn't: %v`, actual)
+	}
+	if actual := time.Since(start); actual > 2*time.Second {
+		t.Fatalf(`task should have been stopped when cancelled; ran for %v`, actual)
+	}
+}
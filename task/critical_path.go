@@ -0,0 +1,139 @@
+package task
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultEstimate is how long a Task is guessed to take when
+// there's no record of it running, and no other Task has one
+// either.
+const DefaultEstimate = time.Second
+
+// Estimate is how long a Task is expected to take, and how long
+// it'll be, once it starts, until everything downstream of it
+// has run.
+type Estimate struct {
+	// Duration is how long the Task itself is expected to take.
+	Duration time.Duration
+
+	// Historic is set when Duration is how long the Task took
+	// the last time it ran. Otherwise it's a guess: the average
+	// of the Tasks that have run, or DefaultEstimate.
+	Historic bool
+
+	// Remaining is Duration plus the Remaining of the dependent
+	// that takes the longest: the length of the longest chain of
+	// Tasks that starts with this one.
+	Remaining time.Duration
+
+	// Next is the dependent on that chain, if there is one.
+	Next string
+}
+
+// Estimates are the Estimates of all the Tasks in a TaskList, by
+// name.
+type Estimates map[string]Estimate
+
+// Estimate works out how long each Task is expected to take from
+// how long they took according to records, like the ones in the
+// Journal of the last run, and from that, how long the chain of
+// Tasks that starts with each Task is.
+func (sl TaskList) Estimate(records Records) Estimates {
+	estimates := make(Estimates, len(sl))
+	var total time.Duration
+	known := 0
+	for name := range sl {
+		if duration := records[name].Duration(); duration > 0 {
+			estimates[name] = Estimate{Duration: duration, Historic: true}
+			total += duration
+			known++
+		}
+	}
+	guess := DefaultEstimate
+	if known > 0 {
+		guess = total / time.Duration(known)
+	}
+	for name := range sl {
+		if _, ok := estimates[name]; !ok {
+			estimates[name] = Estimate{Duration: guess}
+		}
+	}
+
+	dependents := make(map[string][]string, len(sl))
+	for _, task := range sl.sorted() {
+		for _, dep := range task.Dependencies {
+			key, _ := parseDependencyName(dep)
+			dependents[key] = append(dependents[key], task.Name)
+		}
+	}
+	// visiting guards against dependency loops, which Validate
+	// reports on its own.
+	visiting := make(map[string]bool, len(sl))
+	done := make(map[string]bool, len(sl))
+	var remaining func(name string) time.Duration
+	remaining = func(name string) time.Duration {
+		estimate := estimates[name]
+		if done[name] || visiting[name] {
+			return estimate.Remaining
+		}
+		visiting[name] = true
+		var longest time.Duration
+		for _, dependent := range dependents[name] {
+			if _, ok := sl[dependent]; !ok {
+				continue
+			}
+			if r := remaining(dependent); r > longest || estimate.Next == `` {
+				longest, estimate.Next = r, dependent
+			}
+		}
+		estimate.Remaining = estimate.Duration + longest
+		estimates[name] = estimate
+		visiting[name], done[name] = false, true
+		return estimate.Remaining
+	}
+	for name := range sl {
+		remaining(name)
+	}
+	return estimates
+}
+
+// CriticalPath returns the names of the Tasks on the longest
+// chain of Tasks in the TaskList, in the order they run in. The
+// whole run can't take less time than that chain.
+func (sl TaskList) CriticalPath(estimates Estimates) []string {
+	var path []string
+	start := ``
+	for _, task := range sl.sorted() {
+		if start == `` || estimates[task.Name].Remaining > estimates[start].Remaining {
+			start = task.Name
+		}
+	}
+	seen := make(map[string]bool, len(sl))
+	for name := start; name != `` && !seen[name]; name = estimates[name].Next {
+		path = append(path, name)
+		seen[name] = true
+	}
+	return path
+}
+
+// Sort sorts Tasks in the order they should start in when they're
+// ready to run at the same time: higher Priority first, then the
+// ones with the longest chain of Tasks remaining, so that the
+// critical path isn't held up, and then by Order.
+func (e Estimates) Sort(tasks []*Task) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return e.before(tasks[i], tasks[j])
+	})
+}
+
+// before tells whether a should start before b.
+func (e Estimates) before(a, b *Task) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if ra, rb := e[a.Name].Remaining, e[b.Name].Remaining; ra != rb {
+		return ra > rb
+	}
+	return a.Order < b.Order
+}
@@ -0,0 +1,120 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+var criticalPathYAML = `---
+Lint:
+  command: rubocop
+Build:
+  command: make
+Test:
+  command: make
+  args: [test]
+  dependencies: [Build]
+Package:
+  command: make
+  args: [package]
+  dependencies: [Test]
+Docs:
+  command: make
+  args: [docs]
+`
+
+// criticalPathHistory is how long the Tasks took last time.
+func criticalPathHistory(durations map[string]time.Duration) Records {
+	started := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	records := make(Records)
+	for name, d := range durations {
+		records[name] = Record{Status: StatusSucceeded, Started: started, Finished: started.Add(d)}
+	}
+	return records
+}
+
+func TestEstimate(t *testing.T) {
+	list, err := getTaskListFromYaml(criticalPathYAML)
+	if err != nil {
+		t.Fatalf(`could not test Estimate: %v`, err)
+	}
+	estimates := list.Estimate(criticalPathHistory(map[string]time.Duration{
+		`Lint`:    5 * time.Second,
+		`Build`:   2 * time.Second,
+		`Test`:    3 * time.Second,
+		`Package`: 2 * time.Second,
+	}))
+	expect := func(name string, duration, remaining time.Duration, historic bool) {
+		t.Helper()
+		e := estimates[name]
+		if e.Duration != duration || e.Remaining != remaining || e.Historic != historic {
+			t.Fatalf(`expected %q to take %v, with %v remaining (historic: %v); got %+v`, name, duration, remaining, historic, e)
+		}
+	}
+	expect(`Lint`, 5*time.Second, 5*time.Second, true)
+	expect(`Build`, 2*time.Second, 7*time.Second, true)
+	expect(`Test`, 3*time.Second, 5*time.Second, true)
+	// Docs has never run, so it's guessed to take the average.
+	expect(`Docs`, 3*time.Second, 3*time.Second, false)
+
+	path := list.CriticalPath(estimates)
+	if fmt.Sprint(path) != `[Build Test Package]` {
+		t.Fatalf(`expected the critical path to be Build, Test, Package; got %v`, path)
+	}
+}
+
+func TestEstimateWithoutHistory(t *testing.T) {
+	list, err := getTaskListFromYaml(criticalPathYAML)
+	if err != nil {
+		t.Fatalf(`could not test Estimate: %v`, err)
+	}
+	estimates := list.Estimate(nil)
+	if actual := estimates[`Build`].Remaining; actual != 3*DefaultEstimate {
+		t.Fatalf(`expected the longest chain to be counted in tasks; got %v`, actual)
+	}
+}
+
+func TestRunAllStartsCriticalPathFirst(t *testing.T) {
+	list, err := getTaskListFromYaml(criticalPathYAML + `Urgent:
+  command: page
+  priority: 1
+`)
+	if err != nil {
+		t.Fatalf(`could not test RunAll: %v`, err)
+	}
+	opts := RunOptions{
+		Jobs: 1,
+		History: criticalPathHistory(map[string]time.Duration{
+			`Lint`:    5 * time.Second,
+			`Build`:   2 * time.Second,
+			`Test`:    3 * time.Second,
+			`Package`: 2 * time.Second,
+			`Docs`:    time.Second,
+			`Urgent`:  time.Second,
+		}),
+	}
+	var order []string
+	var mtx sync.Mutex
+	run := fakeRunner(nil)
+	recording := func(ctx context.Context, s *Task, handler func(*Task)) error {
+		mtx.Lock()
+		order = append(order, s.Name)
+		mtx.Unlock()
+		return run(ctx, s, handler)
+	}
+	if err := list.runAll(context.Background(), opts, func(*Task) {}, recording); err != nil {
+		t.Fatalf(`could not run tasks: %v`, err)
+	}
+	// Urgent goes first for its priority, then Build, whose chain
+	// is the longest. Once Build is done, Test and Lint tie, so
+	// only the ends are checked.
+	if actual := fmt.Sprint(order[:2]); actual != `[Urgent Build]` {
+		t.Fatalf(`expected Urgent, then Build, to start first; order was %v`, order)
+	}
+	if actual := fmt.Sprint(order[len(order)-1:]); actual != `[Docs]` {
+		t.Fatalf(`expected Docs, with the shortest chain, to start last; order was %v`, order)
+	}
+}
@@ -0,0 +1,28 @@
+package task
+
+import (
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that is written in the task file
+// as a string like "90s" or "1h30m".
+type Duration time.Duration
+
+// UnmarshalYAML parses the duration string from the task file.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var str string
+	if err := unmarshal(&str); err != nil {
+		return err
+	}
+	dur, err := time.ParseDuration(str)
+	if err != nil {
+		return fmt.Errorf(`invalid duration %q: %w`, str, err)
+	}
+	*d = Duration(dur)
+	return nil
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
@@ -0,0 +1,23 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestDurationUnmarshalYAML(t *testing.T) {
+	var holder struct {
+		D Duration `yaml:"d"`
+	}
+	if err := yaml.Unmarshal([]byte(`d: 1m30s`), &holder); err != nil {
+		t.Fatalf(`could not unmarshal duration: %v`, err)
+	}
+	if actual := time.Duration(holder.D); actual != 90*time.Second {
+		t.Fatalf(`expected duration to be 1m30s; was %v`, actual)
+	}
+	if err := yaml.Unmarshal([]byte(`d: soon`), &holder); err == nil {
+		t.Fatalf(`expected an error unmarshaling "soon"; received none`)
+	}
+}
@@ -0,0 +1,110 @@
+package task
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// FailureReason is an enum for why a Task failed.
+type FailureReason string
+
+const (
+	// FailureStart means the command couldn't be started, like
+	// when it isn't installed.
+	FailureStart FailureReason = `start`
+
+	// FailureReturnCode means the command returned something
+	// other than the ExpectedReturnCode.
+	FailureReturnCode FailureReason = `returnCode`
+
+	// FailureStdOut means STDOUT didn't match the
+	// ExpectedStdOutRegex.
+	FailureStdOut FailureReason = `stdout`
+
+	// FailureStdErr means STDERR didn't match the
+	// ExpectedStdErrRegex.
+	FailureStdErr FailureReason = `stderr`
+
+	// FailureTimedOut means the command ran longer than the
+	// Timeout.
+	FailureTimedOut FailureReason = `timedOut`
+
+	// FailureOutputs means the outputs the Task passes on, or
+	// the ones it uses, couldn't be found.
+	FailureOutputs FailureReason = `outputs`
+
+	// FailureRead means the command's output couldn't be read.
+	FailureRead FailureReason = `read`
+)
+
+// Failure is one reason a Task failed. A Task whose command
+// returns the wrong code and prints the wrong thing has more than
+// one.
+type Failure struct {
+	Reason FailureReason `yaml:"reason" json:"reason"`
+
+	// ExpectedReturnCode and ReturnCode are set for
+	// FailureReturnCode.
+	ExpectedReturnCode int `yaml:"expectedReturnCode,omitempty" json:"expectedReturnCode,omitempty"`
+	ReturnCode         int `yaml:"returnCode,omitempty" json:"returnCode,omitempty"`
+
+	// Pattern is the regular expression that didn't match, for
+	// FailureStdOut, FailureStdErr, and FailureOutputs when it's
+	// the outputs pattern.
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+
+	// Message describes the Failure.
+	Message string `yaml:"message" json:"message"`
+}
+
+func (f Failure) String() string {
+	return f.Message
+}
+
+// newFailure makes a Failure with a Message for the reasons that
+// are only described by one.
+func newFailure(reason FailureReason, format string, a ...interface{}) Failure {
+	return Failure{Reason: reason, Message: fmt.Sprintf(format, a...)}
+}
+
+// GetFailures gets the reasons the Task's last attempt failed
+// atomically. There aren't any if it didn't.
+func (s *Task) GetFailures() []Failure {
+	return s.results.GetFailures()
+}
+
+// checkExpectations compares the finished command's return code
+// and output with the Task's expectations, and returns the ways
+// they're different.
+func (s *Task) checkExpectations() []Failure {
+	var failures []Failure
+	if actual := s.results.GetReturnCode(); actual != s.ExpectedReturnCode {
+		failures = append(failures, Failure{
+			Reason:             FailureReturnCode,
+			ExpectedReturnCode: s.ExpectedReturnCode,
+			ReturnCode:         actual,
+			Message:            fmt.Sprintf(`return code was %d, expected %d`, actual, s.ExpectedReturnCode),
+		})
+	}
+	if s.ExpectedStdOutRegex != `` {
+		pattern := regexp.MustCompile(s.ExpectedStdOutRegex)
+		if !pattern.MatchString(s.results.GetStdOut()) {
+			failures = append(failures, Failure{
+				Reason:  FailureStdOut,
+				Pattern: s.ExpectedStdOutRegex,
+				Message: fmt.Sprintf(`STDOUT didn't match %q`, s.ExpectedStdOutRegex),
+			})
+		}
+	}
+	if s.ExpectedStdErrRegex != `` {
+		pattern := regexp.MustCompile(s.ExpectedStdErrRegex)
+		if !pattern.MatchString(s.results.GetStdErr()) {
+			failures = append(failures, Failure{
+				Reason:  FailureStdErr,
+				Pattern: s.ExpectedStdErrRegex,
+				Message: fmt.Sprintf(`STDERR didn't match %q`, s.ExpectedStdErrRegex),
+			})
+		}
+	}
+	return failures
+}
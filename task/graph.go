@@ -0,0 +1,95 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// graphColor is the color to draw a Task in a graph, given how
+// it turned out in an earlier run.
+func graphColor(status Status) string {
+	switch status {
+	case StatusSucceeded, StatusUpToDate:
+		return `#a6e3a1`
+	case StatusFailed, StatusTimedOut:
+		return `#f38ba8`
+	case StatusDependenciesNotMet:
+		return `#cdd6f4`
+	default:
+		return ``
+	}
+}
+
+// WriteDOT draws the TaskList as a Graphviz DOT graph, with arrows
+// from each Task to the Tasks that depend on it. Negated dependencies
+// are dashed. If records from an earlier run are given, the Tasks
+// are colored by how they turned out.
+func (sl TaskList) WriteDOT(w io.Writer, records Records) error {
+	quote := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	b := new(strings.Builder)
+	fmt.Fprintln(b, `digraph fac {`)
+	fmt.Fprintln(b, `  rankdir=LR;`)
+	fmt.Fprintln(b, `  node [shape=box];`)
+	names := sl.names()
+	for _, name := range names {
+		record, ok := records[name]
+		if color := graphColor(record.Status); ok && color != `` {
+			fmt.Fprintf(b, "  \"%s\" [style=filled, fillcolor=\"%s\", tooltip=\"%s\"];\n", quote.Replace(name), color, record.Status)
+		} else {
+			fmt.Fprintf(b, "  \"%s\";\n", quote.Replace(name))
+		}
+	}
+	for _, name := range names {
+		for _, dep := range sl[name].Dependencies {
+			key, positive := parseDependencyName(dep)
+			fmt.Fprintf(b, `  "%s" -> "%s"`, quote.Replace(key), quote.Replace(name))
+			if !positive {
+				fmt.Fprint(b, ` [style=dashed, color="#d20f39", label="on failure"]`)
+			}
+			fmt.Fprintln(b, `;`)
+		}
+	}
+	fmt.Fprintln(b, `}`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteMermaid draws the TaskList as a Mermaid flowchart, the same
+// way WriteDOT does.
+func (sl TaskList) WriteMermaid(w io.Writer, records Records) error {
+	quote := strings.NewReplacer(`"`, `#quot;`)
+	b := new(strings.Builder)
+	fmt.Fprintln(b, `flowchart LR`)
+	names := sl.names()
+	ids := make(map[string]string, len(names))
+	for i, name := range names {
+		ids[name] = fmt.Sprintf(`t%d`, i)
+		fmt.Fprintf(b, "  %s[\"%s\"]\n", ids[name], quote.Replace(name))
+	}
+	for _, name := range names {
+		for _, dep := range sl[name].Dependencies {
+			key, positive := parseDependencyName(dep)
+			from, ok := ids[key]
+			if !ok {
+				// Unknown dependencies get a node of their own.
+				from = fmt.Sprintf(`t%d`, len(ids))
+				ids[key] = from
+				fmt.Fprintf(b, "  %s[\"%s\"]\n", from, quote.Replace(key))
+			}
+			if positive {
+				fmt.Fprintf(b, "  %s --> %s\n", from, ids[name])
+			} else {
+				fmt.Fprintf(b, "  %s -. on failure .-> %s\n", from, ids[name])
+			}
+		}
+	}
+	for _, name := range names {
+		record, ok := records[name]
+		if color := graphColor(record.Status); ok && color != `` {
+			fmt.Fprintf(b, "  style %s fill:%s\n", ids[name], color)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,56 @@
+package task
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	list, err := getTaskListFromYaml(exampleYAML)
+	if err != nil {
+		t.Fatalf(`could not test WriteDOT: %v`, err)
+	}
+	records := Records{
+		`Clear Logs`:   {Status: StatusFailed, ReturnCode: 1},
+		`Only On Fail`: {Status: StatusSucceeded},
+	}
+	out := new(strings.Builder)
+	if err := list.WriteDOT(out, records); err != nil {
+		t.Fatalf(`could not write DOT: %v`, err)
+	}
+	expected := `digraph fac {
+  rankdir=LR;
+  node [shape=box];
+  "Clear Logs" [style=filled, fillcolor="#f38ba8", tooltip="Failed"];
+  "Only On Fail" [style=filled, fillcolor="#a6e3a1", tooltip="Succeeded"];
+  "Update Bundler";
+  "Clear Logs" -> "Only On Fail" [style=dashed, color="#d20f39", label="on failure"];
+  "Clear Logs" -> "Update Bundler";
+}
+`
+	if actual := out.String(); actual != expected {
+		t.Fatalf("expected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	list, err := getTaskListFromYaml(exampleYAML)
+	if err != nil {
+		t.Fatalf(`could not test WriteMermaid: %v`, err)
+	}
+	out := new(strings.Builder)
+	if err := list.WriteMermaid(out, Records{`Clear Logs`: {Status: StatusSucceeded}}); err != nil {
+		t.Fatalf(`could not write Mermaid: %v`, err)
+	}
+	expected := `flowchart LR
+  t0["Clear Logs"]
+  t1["Only On Fail"]
+  t2["Update Bundler"]
+  t0 -. on failure .-> t1
+  t0 --> t2
+  style t0 fill:#a6e3a1
+`
+	if actual := out.String(); actual != expected {
+		t.Fatalf("expected:\n%s\nactual:\n%s", expected, actual)
+	}
+}
@@ -0,0 +1,152 @@
+package task
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// journalEntry is one line of a Journal: the Record of a Task
+// when its Status changed.
+type journalEntry struct {
+	Task string `json:"task"`
+	Record
+}
+
+// Journal keeps a record of a run on disk as it goes, so that if
+// it fails (or fac is stopped), a later run can pick up where it
+// left off. Each time a Task's Status changes, its Record is
+// appended to the journal file as a line of JSON.
+type Journal struct {
+	file     *os.File
+	encoder  *json.Encoder
+	statuses map[string]Status
+	err      error
+	mtx      sync.Mutex
+}
+
+// OpenJournal starts a new journal file at path, replacing any
+// that's there, with the Records of the Tasks in the TaskList that
+// are already finished, like the ones picked up with Resume.
+func OpenJournal(path string, list TaskList) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf(`couldn't create journal directory: %w`, err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf(`couldn't create journal: %w`, err)
+	}
+	j := &Journal{
+		file:     file,
+		encoder:  json.NewEncoder(file),
+		statuses: make(map[string]Status, len(list)),
+	}
+	for _, task := range list.sorted() {
+		if task.GetStatus().IsFinal() {
+			j.Update(task)
+		}
+	}
+	return j, j.Err()
+}
+
+// Update appends the Task's Record to the journal if its Status
+// has changed since the last time. It is safe to call from several
+// goroutines at once.
+func (j *Journal) Update(t *Task) {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	status := t.GetStatus()
+	if last, ok := j.statuses[t.Name]; ok && last == status {
+		return
+	}
+	j.statuses[t.Name] = status
+	if err := j.encoder.Encode(journalEntry{Task: t.Name, Record: t.Record()}); err != nil && j.err == nil {
+		j.err = fmt.Errorf(`couldn't write journal: %w`, err)
+	}
+}
+
+// Handler wraps a handler for TaskList.RunAll so that the journal
+// is updated before it's called.
+func (j *Journal) Handler(handler func(*Task)) func(*Task) {
+	return func(t *Task) {
+		j.Update(t)
+		handler(t)
+	}
+}
+
+// Err returns the first error writing the journal, if there was
+// one.
+func (j *Journal) Err() error {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	return j.err
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	if err := j.file.Close(); err != nil {
+		return fmt.Errorf(`couldn't close journal: %w`, err)
+	}
+	return j.Err()
+}
+
+// ReadJournal reads the last Record of each Task from a journal
+// file written by a Journal.
+func ReadJournal(path string) (Records, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf(`couldn't read journal: %w`, err)
+	}
+	defer file.Close()
+	records := make(Records)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1<<30)
+	// A run that was killed may have left half a line at the
+	// end, which is all right, but not anywhere else.
+	var bad error
+	for line := 1; scanner.Scan(); line++ {
+		if bad != nil {
+			return nil, bad
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			bad = fmt.Errorf(`couldn't parse journal %q, line %d: %w`, path, line, err)
+			continue
+		}
+		records[entry.Task] = entry.Record
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf(`couldn't read journal: %w`, err)
+	}
+	return records, nil
+}
+
+// Resume picks up where an earlier run left off: the Tasks that
+// succeeded (or were up to date) in it, according to its Records,
+// are marked that way again, with their output, so that RunAll
+// only runs the ones that failed, weren't run because their
+// dependencies weren't met, or weren't run at all. It returns how
+// many Tasks it picked up.
+func (sl TaskList) Resume(records Records) int {
+	count := 0
+	for name, task := range sl {
+		record, ok := records[name]
+		if !ok || !record.Status.IsSuccess() {
+			continue
+		}
+		task.results.Atomic(func(r Results) {
+			r.SetStatus(record.Status)
+			r.SetReturnCode(record.ReturnCode)
+			r.SetStdOut(record.StdOut)
+			r.SetStdErr(record.StdErr)
+			r.SetOutputs(record.Outputs)
+			r.SetStarted(record.Started)
+			r.SetFinished(record.Finished)
+		})
+		count++
+	}
+	return count
+}
@@ -0,0 +1,121 @@
+package task
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var journalYAML = `---
+Update Repo:
+  command: git
+Update Gems:
+  command: bundle
+  dependencies: [Update Repo]
+Migrate DB:
+  command: rake
+  dependencies: [Update Gems]
+Lint:
+  command: rubocop
+`
+
+func TestJournalResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `.fac`, `journal.jsonl`)
+	run := func(outcomes map[string]Status, resume bool) []string {
+		list, err := getTaskListFromYaml(journalYAML)
+		if err != nil {
+			t.Fatalf(`could not test the journal: %v`, err)
+		}
+		if resume {
+			records, err := ReadJournal(path)
+			if err != nil {
+				t.Fatalf(`could not read the journal: %v`, err)
+			}
+			if actual := list.Resume(records); actual != 2 {
+				t.Fatalf(`expected to pick up 2 tasks; picked up %d`, actual)
+			}
+		}
+		journal, err := OpenJournal(path, list)
+		if err != nil {
+			t.Fatalf(`could not open the journal: %v`, err)
+		}
+		var mtx sync.Mutex
+		ran := make([]string, 0)
+		fake := fakeRunner(outcomes)
+		counting := func(ctx context.Context, s *Task, handler func(*Task)) error {
+			mtx.Lock()
+			ran = append(ran, s.Name)
+			mtx.Unlock()
+			return fake(ctx, s, handler)
+		}
+		if err := list.runAll(context.Background(), RunOptions{}, journal.Handler(func(*Task) {}), counting); err != nil {
+			t.Fatalf(`could not run tasks: %v`, err)
+		}
+		if err := journal.Close(); err != nil {
+			t.Fatalf(`could not close the journal: %v`, err)
+		}
+		sort.Strings(ran)
+		return ran
+	}
+
+	run(map[string]Status{`Update Gems`: StatusFailed}, false)
+	records, err := ReadJournal(path)
+	if err != nil {
+		t.Fatalf(`could not read the journal: %v`, err)
+	}
+	expected := map[string]Status{
+		`Update Repo`: StatusSucceeded,
+		`Update Gems`: StatusFailed,
+		`Migrate DB`:  StatusDependenciesNotMet,
+		`Lint`:        StatusSucceeded,
+	}
+	for name, status := range expected {
+		if actual := records[name].Status; actual != status {
+			t.Fatalf(`expected the journal to say %q was %v; said %v`, name, status, actual)
+		}
+	}
+
+	ran := run(nil, true)
+	if actual := strings.Join(ran, `, `); actual != `Migrate DB, Update Gems` {
+		t.Fatalf(`expected only the tasks that didn't succeed to run again; ran %s`, actual)
+	}
+	records, err = ReadJournal(path)
+	if err != nil {
+		t.Fatalf(`could not read the journal: %v`, err)
+	}
+	for name := range expected {
+		if actual := records[name].Status; actual != StatusSucceeded {
+			t.Fatalf(`expected the new journal to say %q succeeded; said %v`, name, actual)
+		}
+	}
+}
+
+func TestReadJournalWithPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), `journal.jsonl`)
+	journal := `{"task":"Lint","status":"Succeeded","returnCode":0}` + "\n" + `{"task":"Migrate DB","stat`
+	if err := ioutil.WriteFile(path, []byte(journal), 0644); err != nil {
+		t.Fatalf(`could not write the journal: %v`, err)
+	}
+	records, err := ReadJournal(path)
+	if err != nil {
+		t.Fatalf(`expected a half-written last line to be ignored; got %v`, err)
+	}
+	if actual := records[`Lint`].Status; actual != StatusSucceeded {
+		t.Fatalf(`expected "Lint" to have succeeded; was %v`, actual)
+	}
+	if err := ioutil.WriteFile(path, []byte(journal+"\n"+journal), 0644); err != nil {
+		t.Fatalf(`could not write the journal: %v`, err)
+	}
+	if _, err := ReadJournal(path); err == nil {
+		t.Fatalf(`expected an error for a broken line in the middle`)
+	}
+	os.Remove(path)
+	if _, err := ReadJournal(path); err == nil {
+		t.Fatalf(`expected an error for a missing journal`)
+	}
+}
@@ -0,0 +1,73 @@
+package task
+
+// Failures returns the Tasks that didn't succeed (or weren't
+// already up to date): the ones that failed or timed out, and
+// the ones that never ran, in the order they appear in the task
+// file.
+//
+// If allowExpected is set, the failures that the task file plans
+// for are left out. Those are the Tasks that failed when another
+// Task depends on them failing (with "!" or "-"), and the Tasks
+// that didn't run only because of such a planned-for outcome,
+// like a "!" dependency that succeeded instead.
+func (sl TaskList) Failures(allowExpected bool) []*Task {
+	negated := make(map[string]bool)
+	for _, task := range sl {
+		for _, dep := range task.Dependencies {
+			if key, positive := parseDependencyName(dep); !positive {
+				negated[key] = true
+			}
+		}
+	}
+
+	memo := make(map[string]bool)
+	var isOK func(task *Task) bool
+	isOK = func(task *Task) bool {
+		if ok, found := memo[task.Name]; found {
+			return ok
+		}
+		// Guard against dependency loops while this Task is
+		// being worked out.
+		memo[task.Name] = false
+		ok := false
+		status := task.GetStatus()
+		switch {
+		case status.IsSuccess():
+			ok = true
+		case status.IsFailure():
+			ok = allowExpected && negated[task.Name]
+		case status == StatusDependenciesNotMet && allowExpected:
+			// Expected if everything that kept it from running
+			// was itself expected.
+			blocked := false
+			ok = true
+			for _, dep := range task.Dependencies {
+				key, positive := parseDependencyName(dep)
+				depTask, found := sl[key]
+				if !found {
+					ok = false
+					continue
+				}
+				depStatus := depTask.GetStatus()
+				blocking := depStatus == StatusDependenciesNotMet ||
+					(positive && depStatus.IsFailure()) ||
+					(!positive && depStatus.IsSuccess())
+				if blocking {
+					blocked = true
+					ok = ok && isOK(depTask)
+				}
+			}
+			ok = ok && blocked
+		}
+		memo[task.Name] = ok
+		return ok
+	}
+
+	failures := make([]*Task, 0)
+	for _, task := range sl.sorted() {
+		if !isOK(task) {
+			failures = append(failures, task)
+		}
+	}
+	return failures
+}
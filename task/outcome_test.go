@@ -0,0 +1,54 @@
+package task
+
+import "testing"
+
+func TestFailures(t *testing.T) {
+	list, err := getTaskListFromYaml(planYAML)
+	if err != nil {
+		t.Fatalf(`could not test Failures: %v`, err)
+	}
+	expect := func(allowExpected bool, expected ...string) {
+		failures := list.Failures(allowExpected)
+		names := make(map[string]bool, len(failures))
+		for _, task := range failures {
+			names[task.Name] = true
+		}
+		if len(names) != len(expected) {
+			t.Fatalf(`expected failures %v (allowExpected=%v); found %v`, expected, allowExpected, names)
+		}
+		for _, name := range expected {
+			if !names[name] {
+				t.Fatalf(`expected failures %v (allowExpected=%v); found %v`, expected, allowExpected, names)
+			}
+		}
+	}
+	set := func(name string, status Status) {
+		list[name].results.SetStatus(status)
+	}
+
+	// The dump loaded, so migrating wasn't necessary.
+	set(`Update Repo`, StatusSucceeded)
+	set(`Update JS Deps`, StatusSucceeded)
+	set(`Update Gems`, StatusSucceeded)
+	set(`Load DB Dump`, StatusSucceeded)
+	set(`Migrate DB`, StatusDependenciesNotMet)
+	expect(false, `Migrate DB`)
+	expect(true)
+
+	// The dump didn't load, so the database was migrated.
+	set(`Load DB Dump`, StatusFailed)
+	set(`Migrate DB`, StatusSucceeded)
+	expect(false, `Load DB Dump`)
+	expect(true)
+
+	// The migration failed too, and nothing planned for that.
+	set(`Migrate DB`, StatusTimedOut)
+	expect(true, `Migrate DB`)
+
+	// The gems didn't update, which nothing planned for, so
+	// neither the dump nor the migration ran.
+	set(`Update Gems`, StatusFailed)
+	set(`Load DB Dump`, StatusDependenciesNotMet)
+	set(`Migrate DB`, StatusDependenciesNotMet)
+	expect(true, `Update Gems`, `Load DB Dump`, `Migrate DB`)
+}
@@ -0,0 +1,302 @@
+package task
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultOutputLimit is how many bytes of each of STDOUT and STDERR
+// are kept in memory for a Task that doesn't set an OutputLimit.
+const DefaultOutputLimit = 4 << 20
+
+// maxLineLength is the longest a Line gets. Longer lines are split
+// into several, all but the last of them Continued.
+const maxLineLength = 64 << 10
+
+// Line is one line of what a command printed, without its
+// newline.
+type Line struct {
+	// Number counts the Lines of the Output from 1, including
+	// the ones that were dropped from memory.
+	Number int `json:"number"`
+
+	// Time is when the first byte of the Line was read.
+	Time time.Time `json:"time"`
+
+	Text string `json:"text"`
+
+	// Continued is set when the Line didn't end with a newline:
+	// it was too long, and goes on in the next Line, or it's
+	// the last thing the command printed.
+	Continued bool `json:"continued,omitempty"`
+}
+
+// Output is what a command printed to STDOUT or STDERR, split into
+// Lines as it's written. Only the last Limit bytes of it are kept
+// in memory. The Lines that are dropped to make room are appended
+// to the spill file, if there is one, so that nothing is lost.
+//
+// An Output isn't safe to use from several goroutines at once. The
+// ResultsProxy it belongs to takes care of that.
+type Output struct {
+	limit     int
+	spillPath string
+
+	// lines is a ring buffer. The oldest Line is at head.
+	lines []Line
+	head  int
+	count int
+	size  int
+
+	number      int
+	dropped     int
+	partial     []byte
+	partialTime time.Time
+
+	spill    *os.File
+	spilled  bool
+	spillErr error
+}
+
+// NewOutput creates an empty Output that keeps at most limit bytes
+// in memory (with no limit if it's zero or less), and spills the
+// rest to the file at spillPath, if it isn't empty. The file is
+// only created if something needs to be spilled.
+func NewOutput(limit int, spillPath string) *Output {
+	return &Output{limit: limit, spillPath: spillPath}
+}
+
+// Write adds what the command printed. Complete lines become Lines
+// straight away; anything after the last newline waits for the
+// rest of its line. It never fails.
+func (o *Output) Write(p []byte) (int, error) {
+	o.write(p, time.Now())
+	return len(p), nil
+}
+
+func (o *Output) write(p []byte, now time.Time) {
+	for len(p) > 0 {
+		if len(o.partial) == 0 {
+			o.partialTime = now
+		}
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			o.partial = append(o.partial, p...)
+			for len(o.partial) > maxLineLength {
+				o.splitPartial(now)
+			}
+			return
+		}
+		o.partial = append(o.partial, p[:i]...)
+		for len(o.partial) > maxLineLength {
+			o.splitPartial(now)
+		}
+		o.push(Line{Time: o.partialTime, Text: string(o.partial)})
+		o.partial = o.partial[:0]
+		p = p[i+1:]
+	}
+}
+
+// splitPartial makes a Continued Line of as much of a partial line
+// that's grown too long as fits, without splitting a character.
+func (o *Output) splitPartial(now time.Time) {
+	cut := validPrefix(o.partial[:maxLineLength])
+	if cut == 0 {
+		cut = maxLineLength
+	}
+	o.push(Line{Time: o.partialTime, Text: string(o.partial[:cut]), Continued: true})
+	o.partial = append(o.partial[:0], o.partial[cut:]...)
+	o.partialTime = now
+}
+
+// validPrefix is the length of b without any UTF-8 character cut
+// off at the end.
+func validPrefix(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
+
+// push adds a Line, dropping the oldest ones if that takes the
+// Output over its limit. At least the newest Line is kept.
+func (o *Output) push(line Line) {
+	o.number++
+	line.Number = o.number
+	if o.count == len(o.lines) {
+		o.grow()
+	}
+	o.lines[(o.head+o.count)%len(o.lines)] = line
+	o.count++
+	o.size += len(line.Text) + 1
+	for o.limit > 0 && o.size > o.limit && o.count > 1 {
+		o.drop()
+	}
+}
+
+func (o *Output) grow() {
+	lines := make([]Line, 2*len(o.lines)+16)
+	for i := 0; i < o.count; i++ {
+		lines[i] = o.lines[(o.head+i)%len(o.lines)]
+	}
+	o.lines, o.head = lines, 0
+}
+
+func (o *Output) drop() {
+	line := o.lines[o.head]
+	o.lines[o.head] = Line{}
+	o.head = (o.head + 1) % len(o.lines)
+	o.count--
+	o.size -= len(line.Text) + 1
+	o.dropped++
+	o.spillLine(line)
+}
+
+// spillLine appends a dropped Line to the spill file, creating it
+// the first time. If that fails, the Line is lost, and Err says
+// why.
+func (o *Output) spillLine(line Line) {
+	if o.spillPath == `` || o.spillErr != nil {
+		return
+	}
+	if o.spill == nil {
+		if err := os.MkdirAll(filepath.Dir(o.spillPath), 0755); err != nil {
+			o.spillErr = fmt.Errorf(`couldn't create spill directory: %w`, err)
+			return
+		}
+		// Once it's been flushed, it's appended to, not replaced.
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if o.spilled {
+			flags = os.O_WRONLY | os.O_APPEND
+		}
+		f, err := os.OpenFile(o.spillPath, flags, 0644)
+		if err != nil {
+			o.spillErr = fmt.Errorf(`couldn't open spill file: %w`, err)
+			return
+		}
+		o.spill, o.spilled = f, true
+	}
+	text := line.Text
+	if !line.Continued {
+		text += "\n"
+	}
+	if _, err := o.spill.WriteString(text); err != nil {
+		o.spillErr = fmt.Errorf(`couldn't write spill file: %w`, err)
+	}
+}
+
+// Flush makes a Continued Line of anything printed after the last
+// newline, once the command has finished, and closes the spill
+// file. More can still be written afterwards.
+func (o *Output) Flush() {
+	if len(o.partial) > 0 {
+		o.push(Line{Time: o.partialTime, Text: string(o.partial), Continued: true})
+		o.partial = o.partial[:0]
+	}
+	if o.spill != nil {
+		if err := o.spill.Close(); err != nil && o.spillErr == nil {
+			o.spillErr = fmt.Errorf(`couldn't close spill file: %w`, err)
+		}
+		o.spill = nil
+	}
+}
+
+// Lines returns a copy of the Lines in memory, oldest first. What
+// was printed after the last newline isn't a Line until the rest
+// of its line comes, or the Output is flushed.
+func (o *Output) Lines() []Line {
+	return o.LinesSince(0)
+}
+
+// LinesSince returns a copy of the Lines in memory that come after
+// the one with the given Number, oldest first, so that following
+// an Output as it's written only copies what's new.
+func (o *Output) LinesSince(number int) []Line {
+	if o == nil || o.count == 0 {
+		return []Line{}
+	}
+	skip := number - o.lines[o.head].Number + 1
+	if skip < 0 {
+		skip = 0
+	}
+	if skip >= o.count {
+		return []Line{}
+	}
+	lines := make([]Line, o.count-skip)
+	for i := range lines {
+		lines[i] = o.lines[(o.head+skip+i)%len(o.lines)]
+	}
+	return lines
+}
+
+// Dropped is how many Lines were dropped from memory to stay
+// under the limit.
+func (o *Output) Dropped() int {
+	if o == nil {
+		return 0
+	}
+	return o.dropped
+}
+
+// Err says why Lines couldn't be spilled, if they couldn't.
+func (o *Output) Err() error {
+	if o == nil {
+		return nil
+	}
+	return o.spillErr
+}
+
+// String returns the text in memory as it was printed. A character
+// that's only partly been read yet is left off the end.
+func (o *Output) String() string {
+	if o == nil {
+		return ``
+	}
+	b := new(strings.Builder)
+	b.Grow(o.size + len(o.partial))
+	for i := 0; i < o.count; i++ {
+		line := o.lines[(o.head+i)%len(o.lines)]
+		b.WriteString(line.Text)
+		if !line.Continued {
+			b.WriteByte('\n')
+		}
+	}
+	b.Write(o.partial[:validPrefix(o.partial)])
+	return b.String()
+}
+
+// unsafeFileName matches the characters in a Task's name that are
+// replaced to make the names of its spill files.
+var unsafeFileName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// spillPath is where the lines of the given stream of an attempt
+// at running the Task that are dropped from memory are spilled,
+// if the Task has somewhere to spill them.
+func (s *Task) spillPath(attempt int, stream string) string {
+	if s.outputDir == `` {
+		return ``
+	}
+	name := unsafeFileName.ReplaceAllString(s.Name, `_`)
+	return filepath.Join(s.outputDir, fmt.Sprintf(`%s.%d.%s`, name, attempt, stream))
+}
+
+// newOutputs creates the Outputs for an attempt at running the
+// Task.
+func (s *Task) newOutputs(attempt int) (*Output, *Output) {
+	limit := s.OutputLimit
+	if limit == 0 {
+		limit = DefaultOutputLimit
+	}
+	return NewOutput(limit, s.spillPath(attempt, `stdout`)), NewOutput(limit, s.spillPath(attempt, `stderr`))
+}
@@ -0,0 +1,119 @@
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutputLines(t *testing.T) {
+	o := NewOutput(0, ``)
+	first := time.Now()
+	o.write([]byte("one\ntw"), first)
+	o.write([]byte("o\nthree"), first.Add(time.Second))
+	lines := o.Lines()
+	if actual := len(lines); actual != 2 {
+		t.Fatalf(`expected 2 complete lines; got %d`, actual)
+	}
+	if actual := lines[1]; actual.Text != `two` || actual.Number != 2 || !actual.Time.Equal(first) {
+		t.Fatalf(`expected the second line to be "two", timed when it started; was %+v`, actual)
+	}
+	if actual := o.String(); actual != "one\ntwo\nthree" {
+		t.Fatalf(`expected the text as it was printed; got %q`, actual)
+	}
+	o.Flush()
+	lines = o.Lines()
+	if actual := lines[len(lines)-1]; actual.Text != `three` || !actual.Continued {
+		t.Fatalf(`expected the unfinished line to be flushed as a continued line; was %+v`, actual)
+	}
+	o.Write([]byte(" and four\n"))
+	if actual := o.String(); actual != "one\ntwo\nthree and four\n" {
+		t.Fatalf(`expected writing after a flush to carry on the text; got %q`, actual)
+	}
+}
+
+func TestOutputUTF8(t *testing.T) {
+	o := NewOutput(0, ``)
+	euro := []byte(`€`)
+	o.Write([]byte(`price: `))
+	o.Write(euro[:2])
+	if actual := o.String(); actual != `price: ` {
+		t.Fatalf(`expected half a character to be left off; got %q`, actual)
+	}
+	o.Write(euro[2:])
+	if actual := o.String(); actual != `price: €` {
+		t.Fatalf(`expected the whole character once it's all there; got %q`, actual)
+	}
+
+	o = NewOutput(0, ``)
+	long := strings.Repeat(`a`, maxLineLength-1) + `€€`
+	o.Write([]byte(long + "\n"))
+	lines := o.Lines()
+	if actual := len(lines); actual != 2 {
+		t.Fatalf(`expected a long line to be split in two; got %d lines`, actual)
+	}
+	if actual := lines[0].Text; !strings.HasSuffix(actual, `a`) || !lines[0].Continued {
+		t.Fatalf(`expected the split not to cut a character in half; first part ends %q`, actual[len(actual)-4:])
+	}
+	if actual := lines[1].Text; actual != `€€` {
+		t.Fatalf(`expected the rest of the line in the second part; got %q`, actual)
+	}
+	if actual := o.String(); actual != long+"\n" {
+		t.Fatalf(`expected the split line to read as it was printed`)
+	}
+}
+
+func TestLinesSince(t *testing.T) {
+	o := NewOutput(10, ``)
+	o.Write([]byte("one\ntwo\nthree\nfour\nfive\n"))
+	texts := func(lines []Line) []string {
+		result := make([]string, len(lines))
+		for i, line := range lines {
+			result[i] = line.Text
+		}
+		return result
+	}
+	expect := func(number int, expected string) {
+		t.Helper()
+		if actual := fmt.Sprint(texts(o.LinesSince(number))); actual != expected {
+			t.Fatalf(`expected the lines since %d to be %s; got %s`, number, expected, actual)
+		}
+	}
+	// Only four and five fit in memory.
+	expect(0, `[four five]`)
+	expect(3, `[four five]`)
+	expect(4, `[five]`)
+	expect(5, `[]`)
+	expect(9, `[]`)
+}
+
+func TestOutputLimit(t *testing.T) {
+	spill := filepath.Join(t.TempDir(), `output`, `Build.1.stdout`)
+	o := NewOutput(10, spill)
+	o.Write([]byte("one\ntwo\nthree\nfour\n"))
+	if actual := o.String(); actual != "four\n" {
+		t.Fatalf(`expected only the newest lines to be kept in memory; got %q`, actual)
+	}
+	if actual := o.Dropped(); actual != 3 {
+		t.Fatalf(`expected 3 lines to be dropped; were %d`, actual)
+	}
+	if actual := o.Lines()[0].Number; actual != 4 {
+		t.Fatalf(`expected line numbers to count the dropped lines; got %d`, actual)
+	}
+	o.Flush()
+	o.Write([]byte("five and six\n"))
+	o.Flush()
+	if err := o.Err(); err != nil {
+		t.Fatalf(`unexpected error spilling: %v`, err)
+	}
+	buff, err := ioutil.ReadFile(spill)
+	if err != nil {
+		t.Fatalf(`expected the dropped lines to be spilled: %v`, err)
+	}
+	if actual := string(buff); actual != "one\ntwo\nthree\nfour\n" {
+		t.Fatalf(`expected the dropped lines in the spill file; got %q`, actual)
+	}
+}
@@ -0,0 +1,270 @@
+package task
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// OutputSpec says where a Task finds the values it passes on to
+// the Tasks that depend on it. Named capture groups in the
+// ExpectedStdOutRegex are captured as well.
+type OutputSpec struct {
+	// Pattern is a regular expression matched against STDOUT.
+	// Each named capture group, like (?P<version>\S+), is an
+	// output. If it doesn't match, the Task fails.
+	Pattern string `yaml:"pattern"`
+
+	// File is a file the command writes KEY=VALUE lines to,
+	// one for each output. Relative paths are relative to the
+	// WorkingDirectory.
+	File string `yaml:"file"`
+}
+
+// GetOutputs gets the values the Task captured for its dependents
+// atomically.
+func (s *Task) GetOutputs() map[string]string {
+	return s.results.GetOutputs()
+}
+
+// captureGroups adds the named groups of pattern that match stdOut
+// to outputs. It reports whether the pattern matched.
+func captureGroups(pattern, stdOut string, outputs map[string]string) bool {
+	re := regexp.MustCompile(pattern)
+	match := re.FindStringSubmatch(stdOut)
+	if match == nil {
+		return false
+	}
+	for i, name := range re.SubexpNames() {
+		if name != `` {
+			outputs[name] = match[i]
+		}
+	}
+	return true
+}
+
+// readOutputsFile reads KEY=VALUE lines into outputs. Blank lines
+// and lines starting with "#" are skipped.
+func readOutputsFile(path string, outputs map[string]string) error {
+	buff, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(buff))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == `` || strings.HasPrefix(text, `#`) {
+			continue
+		}
+		i := strings.IndexByte(text, '=')
+		if i < 1 {
+			return fmt.Errorf(`%s:%d: expected KEY=VALUE: %q`, path, line, text)
+		}
+		outputs[strings.TrimSpace(text[:i])] = strings.TrimSpace(text[i+1:])
+	}
+	return scanner.Err()
+}
+
+// captureOutputs collects the Task's outputs once its command has
+// succeeded. It returns a Failure if they couldn't be found.
+func (s *Task) captureOutputs() *Failure {
+	outputs := make(map[string]string)
+	stdOut := s.results.GetStdOut()
+	if s.ExpectedStdOutRegex != `` {
+		captureGroups(s.ExpectedStdOutRegex, stdOut, outputs)
+	}
+	if s.Outputs.Pattern != `` && !captureGroups(s.Outputs.Pattern, stdOut, outputs) {
+		return &Failure{
+			Reason:  FailureOutputs,
+			Pattern: s.Outputs.Pattern,
+			Message: fmt.Sprintf(`outputs pattern didn't match STDOUT: %q`, s.Outputs.Pattern),
+		}
+	}
+	if path := s.Outputs.File; path != `` {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(s.WorkingDirectory, path)
+		}
+		if err := readOutputsFile(path, outputs); err != nil {
+			failure := newFailure(FailureOutputs, `couldn't read outputs file: %v`, err)
+			return &failure
+		}
+	}
+	s.results.SetOutputs(outputs)
+	return nil
+}
+
+// templates are a Task's Command, Args and Environment as they
+// were written in the task file, before fillOutputs filled in the
+// outputs of other Tasks.
+type templates struct {
+	command     string
+	args        []string
+	environment map[string]string
+}
+
+// outputsData is what the templates in a Task can refer to: the
+// outputs of the Tasks it depends on, as
+// {{ .Tasks.Build.outputs.version }}.
+func (sl TaskList) outputsData(task *Task) map[string]interface{} {
+	tasks := make(map[string]interface{}, len(task.Dependencies))
+	for _, dep := range task.Dependencies {
+		key, _ := parseDependencyName(dep)
+		if depTask, ok := sl[key]; ok {
+			tasks[key] = map[string]interface{}{`outputs`: depTask.GetOutputs()}
+		}
+	}
+	return map[string]interface{}{`Tasks`: tasks}
+}
+
+// referencedTasks returns the names of the Tasks whose outputs a
+// parsed template refers to, as .Tasks.Build or as
+// index .Tasks "Update Repo", in the order they come in.
+func referencedTasks(tree *parse.Tree) []string {
+	names := make([]string, 0)
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			if len(n.Args) >= 3 {
+				ident, isIdent := n.Args[0].(*parse.IdentifierNode)
+				field, isField := n.Args[1].(*parse.FieldNode)
+				name, isString := n.Args[2].(*parse.StringNode)
+				if isIdent && ident.Ident == `index` && isField && isString &&
+					len(field.Ident) == 1 && field.Ident[0] == `Tasks` {
+					names = append(names, name.Text)
+				}
+			}
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.FieldNode:
+			if len(n.Ident) > 1 && n.Ident[0] == `Tasks` {
+				names = append(names, n.Ident[1])
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+	walk(tree.Root)
+	return names
+}
+
+// outputsTemplate matches a template action that refers to other
+// Tasks' outputs. Only text with one of those is a template: other
+// {{ ... }}, like the Go templates that docker inspect -f and
+// kubectl -o go-template take, are passed on as they are.
+var outputsTemplate = regexp.MustCompile(`\{\{[^}]*\.Tasks\b`)
+
+// isTemplate tells whether text refers to other Tasks' outputs.
+func isTemplate(text string) bool {
+	return outputsTemplate.MatchString(text)
+}
+
+// hasTemplates tells whether any of the Task's Command, Args or
+// Environment values refer to other Tasks' outputs.
+func (s *Task) hasTemplates() bool {
+	if s.templates != nil || isTemplate(s.Command) {
+		return true
+	}
+	for _, arg := range s.Args {
+		if isTemplate(arg) {
+			return true
+		}
+	}
+	for _, value := range s.Environment {
+		if isTemplate(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// execute fills in a template with data. Text that doesn't refer to
+// other Tasks' outputs is returned as is.
+func execute(text string, data interface{}) (string, error) {
+	if !isTemplate(text) {
+		return text, nil
+	}
+	tmpl, err := template.New(``).Option(`missingkey=error`).Parse(text)
+	if err != nil {
+		return ``, err
+	}
+	b := new(strings.Builder)
+	if err := tmpl.Execute(b, data); err != nil {
+		return ``, err
+	}
+	return b.String(), nil
+}
+
+// fillOutputs fills in the outputs of the Tasks it depends on that
+// the Task's Command, Args and Environment values refer to. It's
+// called just before the Task is launched, once those Tasks have
+// finished.
+func (s *Task) fillOutputs(sl TaskList) error {
+	if !s.hasTemplates() {
+		return nil
+	}
+	if s.templates == nil {
+		s.templates = &templates{
+			command:     s.Command,
+			args:        append([]string{}, s.Args...),
+			environment: make(map[string]string, len(s.Environment)),
+		}
+		for key, value := range s.Environment {
+			s.templates.environment[key] = value
+		}
+	}
+	data := sl.outputsData(s)
+	command, err := execute(s.templates.command, data)
+	if err != nil {
+		return fmt.Errorf(`couldn't fill in command: %w`, err)
+	}
+	args := make([]string, len(s.templates.args))
+	for i, arg := range s.templates.args {
+		if args[i], err = execute(arg, data); err != nil {
+			return fmt.Errorf(`couldn't fill in args: %w`, err)
+		}
+	}
+	environment := make(map[string]string, len(s.templates.environment))
+	for key, value := range s.templates.environment {
+		if environment[key], err = execute(value, data); err != nil {
+			return fmt.Errorf(`couldn't fill in environment %s: %w`, key, err)
+		}
+	}
+	s.Command, s.Args, s.Environment = command, args, environment
+	return nil
+}
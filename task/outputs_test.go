@@ -0,0 +1,95 @@
+package task
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputs(t *testing.T) {
+	dir := t.TempDir()
+	list, err := getTaskListFromYaml(`---
+Build:
+  run: echo "built version 1.2.3"
+  outputs:
+    pattern: version (?P<version>\S+)
+Upload:
+  run: echo "ID=42" > upload.env
+  workingDirectory: ` + dir + `
+  outputs:
+    file: upload.env
+Deploy:
+  command: sh
+  args: ["-c", "echo deploying $VERSION as {{ .Tasks.Upload.outputs.ID }}"]
+  environment:
+    VERSION: v{{ .Tasks.Build.outputs.version }}
+  dependencies: [Build, Upload]
+Announce:
+  command: echo
+  args: ["{{ .Tasks.Build.outputs.missing }}"]
+  dependencies: [Build]
+`)
+	if err != nil {
+		t.Fatalf(`could not test outputs: %v`, err)
+	}
+	if err := list.RunAll(context.Background(), RunOptions{}, func(*Task) {}); err != nil {
+		t.Fatalf(`could not run tasks: %v`, err)
+	}
+	if actual := list[`Build`].GetOutputs()[`version`]; actual != `1.2.3` {
+		t.Fatalf(`expected "Build" to capture version 1.2.3; captured %q`, actual)
+	}
+	if actual := list[`Deploy`].GetStdOut(); actual != "deploying v1.2.3 as 42\n" {
+		t.Fatalf(`expected "Deploy" to be given the outputs of Build and Upload; printed %q`, actual)
+	}
+	announce := list[`Announce`]
+	if actual := announce.GetStatus(); actual != StatusFailed {
+		t.Fatalf(`expected "Announce" to fail without the output it needs; was %v`, actual)
+	}
+	if actual := announce.GetFailures(); len(actual) != 1 || actual[0].Reason != FailureOutputs || !strings.Contains(actual[0].Message, `missing`) {
+		t.Fatalf(`expected "Announce" to explain which output was missing; said %#v`, actual)
+	}
+}
+
+func TestOutputsPatternMismatch(t *testing.T) {
+	task := newSuccessfulTask()
+	task.Outputs.Pattern = `version (?P<version>\S+)`
+	err := task.Run(context.Background(), func(s *Task) {})
+	if err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusFailed {
+		t.Fatalf(`expected the task to fail when its outputs pattern doesn't match; was %v`, actual)
+	}
+	if actual := task.GetFailures(); len(actual) != 1 || actual[0].Pattern != task.Outputs.Pattern {
+		t.Fatalf(`expected the failure to name the outputs pattern; got %#v`, actual)
+	}
+}
+
+func TestReadOutputsFile(t *testing.T) {
+	outputs := make(map[string]string)
+	err := readOutputsFile(filepath.Join(`test_data`, `success_data.txt`), outputs)
+	if err == nil {
+		t.Fatalf(`expected an error for a file that isn't KEY=VALUE lines`)
+	}
+}
+
+func TestOtherTemplatesPassedThrough(t *testing.T) {
+	task := newSuccessfulTask()
+	task.Command = `echo`
+	task.Args = []string{`{{.State.Running}}`, `{{json .Config}}`}
+	task.ExpectedStdOutRegex = ``
+	task.Dependencies = nil
+	if problems := (TaskList{task.Name: task}).Validate(); len(problems) > 0 {
+		t.Fatalf(`expected templates that aren't fac's to be valid; got %v`, problems)
+	}
+	if err := task.fillOutputs(TaskList{task.Name: task}); err != nil {
+		t.Fatalf(`expected templates that aren't fac's to be left alone; got %v`, err)
+	}
+	if err := task.Run(context.Background(), func(s *Task) {}); err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStdOut(); actual != "{{.State.Running}} {{json .Config}}\n" {
+		t.Fatalf(`expected the arguments to be passed on unchanged; printed %q`, actual)
+	}
+}
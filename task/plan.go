@@ -0,0 +1,67 @@
+package task
+
+import "sort"
+
+// Plan works out the order the Tasks would run in without running
+// any of them. It returns the Tasks in waves: each wave is the Tasks
+// that would start together once the waves before them were done,
+// in the order they'd start in.
+// Every Task is assumed to succeed, except the ones named in
+// failures, which are assumed to fail. Tasks that would never run
+// because their dependencies weren't met are returned separately.
+func (sl TaskList) Plan(failures map[string]bool) (waves [][]*Task, skipped []*Task, err error) {
+	// Simulate the run on copies of the Tasks, so that their
+	// results aren't touched.
+	sim := make(TaskList, len(sl))
+	for name, task := range sl {
+		sim[name] = &Task{
+			Name:         task.Name,
+			Dependencies: task.Dependencies,
+			Order:        task.Order,
+			Priority:     task.Priority,
+			results:      NewResultsProxy(),
+		}
+	}
+	for {
+		ready, err := sim.ReadyToRun()
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(ready) == 0 {
+			break
+		}
+		wave := make([]*Task, len(ready))
+		for i, task := range ready {
+			wave[i] = sl[task.Name]
+		}
+		// Finish the whole wave before looking for the next, so
+		// that a wave only holds Tasks that could start together.
+		for _, task := range ready {
+			if failures[task.Name] {
+				task.results.SetStatus(StatusFailed)
+			} else {
+				task.results.SetStatus(StatusSucceeded)
+			}
+		}
+		waves = append(waves, wave)
+	}
+	for _, task := range sim.sorted() {
+		if task.GetStatus() != StatusSucceeded && task.GetStatus() != StatusFailed {
+			skipped = append(skipped, sl[task.Name])
+		}
+	}
+	return waves, skipped, nil
+}
+
+// sorted returns the Tasks in the order they appear in the
+// task file.
+func (sl TaskList) sorted() []*Task {
+	slice := make([]*Task, 0, len(sl))
+	for _, task := range sl {
+		slice = append(slice, task)
+	}
+	sort.Slice(slice, func(i, j int) bool {
+		return slice[i].Order < slice[j].Order
+	})
+	return slice
+}
@@ -0,0 +1,66 @@
+package task
+
+import "testing"
+
+var planYAML = `---
+Update Repo:
+  command: git
+Update JS Deps:
+  command: npm
+  dependencies: [Update Repo]
+Update Gems:
+  command: bundle
+  dependencies: [Update Repo]
+Load DB Dump:
+  command: pgrestore
+  dependencies: [Update Gems]
+Migrate DB:
+  command: rake
+  dependencies: ["! Load DB Dump"]
+`
+
+func planNames(waves [][]*Task) [][]string {
+	names := make([][]string, len(waves))
+	for i, wave := range waves {
+		for _, task := range wave {
+			names[i] = append(names[i], task.Name)
+		}
+	}
+	return names
+}
+
+func TestPlan(t *testing.T) {
+	list, err := getTaskListFromYaml(planYAML)
+	if err != nil {
+		t.Fatalf(`could not test Plan: %v`, err)
+	}
+	expect := func(failures map[string]bool, expectedWaves []int, expectedSkipped int) {
+		waves, skipped, err := list.Plan(failures)
+		if err != nil {
+			t.Fatalf(`could not plan: %v`, err)
+		}
+		if len(waves) != len(expectedWaves) {
+			t.Fatalf(`expected %d waves; found %v`, len(expectedWaves), planNames(waves))
+		}
+		for i, size := range expectedWaves {
+			if len(waves[i]) != size {
+				t.Fatalf(`expected wave %d to have %d tasks; found %v`, i+1, size, planNames(waves))
+			}
+		}
+		if len(skipped) != expectedSkipped {
+			t.Fatalf(`expected %d tasks to be skipped; found %d`, expectedSkipped, len(skipped))
+		}
+		if actual := waves[0][0]; actual != list[`Update Repo`] {
+			t.Fatalf(`expected "Update Repo" to be in the first wave; found %q`, actual.Name)
+		}
+		if actual := list[`Update Repo`].GetStatus(); actual != StatusNotRun {
+			t.Fatalf(`expected planning not to change the tasks' status; was %v`, actual)
+		}
+	}
+	// "Migrate DB" only runs if "Load DB Dump" fails.
+	expect(nil, []int{1, 2, 1}, 1)
+	expect(map[string]bool{`Load DB Dump`: true}, []int{1, 2, 1, 1}, 0)
+	// If "Update Gems" fails, neither "Load DB Dump" nor
+	// "Migrate DB" run.
+	expect(map[string]bool{`Update Gems`: true}, []int{1, 2}, 2)
+}
@@ -0,0 +1,41 @@
+package task
+
+import (
+	"context"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// killGracePeriod is how long a Task's process group has to exit
+// after being asked to terminate before it is killed outright.
+var killGracePeriod = 5 * time.Second
+
+// supervise watches ctx while cmd runs. If ctx is done before the
+// command exits, it asks the command's whole process group to
+// terminate, then kills it outright if it hasn't exited within
+// killGracePeriod. The returned function must be called once the
+// command has exited, and reports whether it had to be stopped.
+func supervise(ctx context.Context, cmd *exec.Cmd) func() bool {
+	exited := make(chan struct{})
+	grace := killGracePeriod
+	var stopped int32
+	go func() {
+		select {
+		case <-exited:
+			return
+		case <-ctx.Done():
+		}
+		atomic.StoreInt32(&stopped, 1)
+		terminateProcessGroup(cmd)
+		select {
+		case <-exited:
+		case <-time.After(grace):
+			killProcessGroup(cmd)
+		}
+	}()
+	return func() bool {
+		close(exited)
+		return atomic.LoadInt32(&stopped) == 1
+	}
+}
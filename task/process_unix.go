@@ -0,0 +1,27 @@
+//go:build !windows
+// +build !windows
+
+package task
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup puts the command in a process group of its own,
+// so that it and all of its children can be signaled together.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminateProcessGroup politely asks the command and all of its
+// children to exit.
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+// killProcessGroup forcibly stops the command and all of its
+// children.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package task
+
+import "os/exec"
+
+// setProcessGroup does nothing on Windows, which has no process
+// groups to signal.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcessGroup stops the command. Windows can't ask a
+// process to exit politely, so this is the same as killing it.
+func terminateProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+// killProcessGroup forcibly stops the command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,94 @@
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Record is what's kept of a Task once a run is over, for
+// commands that look back at earlier runs.
+type Record struct {
+	Status     Status            `yaml:"status" json:"status"`
+	ReturnCode int               `yaml:"returnCode" json:"returnCode"`
+	Attempts   int               `yaml:"attempts,omitempty" json:"attempts,omitempty"`
+	Started    time.Time         `yaml:"started,omitempty" json:"started,omitempty"`
+	Finished   time.Time         `yaml:"finished,omitempty" json:"finished,omitempty"`
+	StdOut     string            `yaml:"stdout,omitempty" json:"stdout,omitempty"`
+	StdErr     string            `yaml:"stderr,omitempty" json:"stderr,omitempty"`
+	Outputs    map[string]string `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+	Failures   []Failure         `yaml:"failures,omitempty" json:"failures,omitempty"`
+}
+
+// Duration is how long the Task ran for, or zero if it didn't
+// finish running.
+func (r Record) Duration() time.Duration {
+	if r.Started.IsZero() || r.Finished.IsZero() {
+		return 0
+	}
+	return r.Finished.Sub(r.Started)
+}
+
+// Record takes the Record of the Task as it stands.
+func (s *Task) Record() Record {
+	record := Record{
+		Status:     s.GetStatus(),
+		ReturnCode: s.results.GetReturnCode(),
+		Started:    s.GetStarted(),
+		Finished:   s.GetFinished(),
+		StdOut:     s.GetStdOut(),
+		StdErr:     s.GetStdErr(),
+		Outputs:    s.GetOutputs(),
+		Failures:   s.GetFailures(),
+	}
+	if !record.Started.IsZero() {
+		record.Attempts = s.GetAttempt()
+	}
+	if len(record.Outputs) == 0 {
+		record.Outputs = nil
+	}
+	if len(record.Failures) == 0 {
+		record.Failures = nil
+	}
+	return record
+}
+
+// Records are the Records of all the Tasks in a run, by name.
+type Records map[string]Record
+
+// Records takes the Record of every Task as it stands.
+func (sl TaskList) Records() Records {
+	records := make(Records, len(sl))
+	for name, task := range sl {
+		records[name] = task.Record()
+	}
+	return records
+}
+
+// ReadRecords reads the Records of a run from a YAML file
+// written by Records.Write.
+func ReadRecords(path string) (Records, error) {
+	buff, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf(`couldn't read records: %w`, err)
+	}
+	records := make(Records)
+	if err := yaml.Unmarshal(buff, &records); err != nil {
+		return nil, fmt.Errorf(`couldn't parse records in %q: %w`, path, err)
+	}
+	return records, nil
+}
+
+// Write writes the Records to a YAML file.
+func (r Records) Write(path string) error {
+	buff, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf(`couldn't serialize records: %w`, err)
+	}
+	if err := ioutil.WriteFile(path, buff, 0644); err != nil {
+		return fmt.Errorf(`couldn't write records: %w`, err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package task
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRecords(t *testing.T) {
+	list, err := getTaskListFromYaml(exampleYAML)
+	if err != nil {
+		t.Fatalf(`could not test Records: %v`, err)
+	}
+	list[`Clear Logs`].results.SetStatus(StatusFailed)
+	list[`Clear Logs`].results.SetReturnCode(3)
+	list[`Only On Fail`].results.SetStatus(StatusSucceeded)
+	list[`Update Bundler`].results.SetStatus(StatusDependenciesNotMet)
+
+	path := filepath.Join(t.TempDir(), `results.yaml`)
+	if err := list.Records().Write(path); err != nil {
+		t.Fatalf(`could not write records: %v`, err)
+	}
+	records, err := ReadRecords(path)
+	if err != nil {
+		t.Fatalf(`could not read records: %v`, err)
+	}
+	expected := Records{
+		`Clear Logs`:     {Status: StatusFailed, ReturnCode: 3},
+		`Only On Fail`:   {Status: StatusSucceeded},
+		`Update Bundler`: {Status: StatusDependenciesNotMet},
+	}
+	if actual := len(records); actual != len(expected) {
+		t.Fatalf(`expected %d records; found %d`, len(expected), actual)
+	}
+	for name, e := range expected {
+		if actual := records[name]; actual.Status != e.Status || actual.ReturnCode != e.ReturnCode {
+			t.Fatalf(`expected record for %q to be %+v; was %+v`, name, e, actual)
+		}
+	}
+}
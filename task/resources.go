@@ -0,0 +1,73 @@
+package task
+
+import "sync"
+
+// resourcePools keeps track of how many of each named resource
+// are free for Tasks to use. A resource without a pool size is
+// a mutex: only one Task may hold it at a time.
+type resourcePools struct {
+	free map[string]int
+	mtx  sync.Mutex
+}
+
+// newResourcePools creates the pools with the sizes given.
+func newResourcePools(sizes map[string]int) *resourcePools {
+	rp := &resourcePools{free: make(map[string]int, len(sizes))}
+	for name, size := range sizes {
+		if size < 1 {
+			size = 1
+		}
+		rp.free[name] = size
+	}
+	return rp
+}
+
+func (rp *resourcePools) freeCount(name string) int {
+	free, ok := rp.free[name]
+	if !ok {
+		free = 1
+		rp.free[name] = free
+	}
+	return free
+}
+
+// unique drops repeated names, since a Task can only hold one
+// of each resource however many times it lists it.
+func unique(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// tryAcquire takes one of each of the named resources, but only
+// if they are all free. Otherwise, it takes none of them and
+// returns false.
+func (rp *resourcePools) tryAcquire(names []string) bool {
+	rp.mtx.Lock()
+	defer rp.mtx.Unlock()
+	names = unique(names)
+	for _, name := range names {
+		if rp.freeCount(name) < 1 {
+			return false
+		}
+	}
+	for _, name := range names {
+		rp.free[name]--
+	}
+	return true
+}
+
+// release gives back the named resources taken by tryAcquire.
+func (rp *resourcePools) release(names []string) {
+	rp.mtx.Lock()
+	defer rp.mtx.Unlock()
+	for _, name := range unique(names) {
+		rp.free[name]++
+	}
+}
@@ -0,0 +1,26 @@
+package task
+
+import "testing"
+
+func TestResourcePools(t *testing.T) {
+	rp := newResourcePools(map[string]int{`db`: 2})
+	expect := func(expected bool, names ...string) {
+		if actual := rp.tryAcquire(names); actual != expected {
+			t.Fatalf(`expected acquiring %v to be %v; was %v`, names, expected, actual)
+		}
+	}
+	expect(true, `db`, `port-3000`)
+	// port-3000 wasn't declared, so it's a mutex.
+	expect(false, `port-3000`)
+	// All or nothing: db must not be taken if port-3000 can't be.
+	expect(false, `db`, `port-3000`)
+	expect(true, `db`)
+	expect(false, `db`)
+	rp.release([]string{`db`, `port-3000`})
+	expect(true, `port-3000`)
+	expect(true, `db`)
+	expect(true)
+	rp.release([]string{`port-3000`})
+	// Listing a resource twice only takes it once.
+	expect(true, `port-3000`, `port-3000`)
+}
@@ -1,8 +1,8 @@
 package task
 
 import (
-	"strings"
 	"sync"
+	"time"
 )
 
 // Results represents the changing state of the Task as the program is run.
@@ -31,37 +31,107 @@ type Results interface {
 
 	// SetStatus replaces the current state of the Task.
 	SetStatus(Status)
+
+	// GetHistory returns the results of the previous attempts
+	// at running the Task.
+	GetHistory() []Attempt
+
+	// SetHistory replaces the results of the previous attempts.
+	SetHistory([]Attempt)
+
+	// GetOutputs returns the values the Task captured for its
+	// dependents to use.
+	GetOutputs() map[string]string
+
+	// SetOutputs replaces the captured values.
+	SetOutputs(map[string]string)
+
+	// GetStarted returns when the Task started running.
+	GetStarted() time.Time
+
+	// SetStarted replaces when the Task started running.
+	SetStarted(time.Time)
+
+	// GetFinished returns when the Task finished running.
+	GetFinished() time.Time
+
+	// SetFinished replaces when the Task finished running.
+	SetFinished(time.Time)
+
+	// GetFailures returns the reasons the current attempt at
+	// running the Task failed.
+	GetFailures() []Failure
+
+	// SetFailures replaces the reasons the Task failed.
+	SetFailures([]Failure)
+}
+
+// Attempt is what's kept of one failed attempt at running a
+// Task that was then retried.
+type Attempt struct {
+	StdOut     string
+	StdErr     string
+	ReturnCode int
 }
 
 type results struct {
-	stdOut     string
-	stdErr     string
+	stdOut     *Output
+	stdErr     *Output
 	returnCode int
 	status     Status
+	history    []Attempt
+	outputs    map[string]string
+	started    time.Time
+	finished   time.Time
+	failures   []Failure
 }
 
 // GetStdOut returns the accumulated text printed to stdout.
 // Implements Results interface.
 func (r *results) GetStdOut() string {
-	return r.stdOut
+	return r.stdOut.String()
 }
 
 // SetStdOut replaces the stored text of stdout.
 // Implements Results interface.
 func (r *results) SetStdOut(stdOut string) {
-	r.stdOut = stdOut
+	r.stdOut = nil
+	r.stdout().Write([]byte(stdOut))
+	// It's all there is, so it's all Lines.
+	r.stdout().Flush()
 }
 
 // GetStdErr returns the accumulated text printed to stderr.
 // Implements Results interface.
 func (r *results) GetStdErr() string {
-	return r.stdErr
+	return r.stdErr.String()
 }
 
 // SetStdErr replaces the store text of stderr.
 // Implements Results interface.
 func (r *results) SetStdErr(stdErr string) {
-	r.stdErr = stdErr
+	r.stdErr = nil
+	r.stderr().Write([]byte(stdErr))
+	// It's all there is, so it's all Lines.
+	r.stderr().Flush()
+}
+
+// stdout is the Output for stdout, which is created with no
+// limit if the Task isn't running.
+func (r *results) stdout() *Output {
+	if r.stdOut == nil {
+		r.stdOut = NewOutput(0, ``)
+	}
+	return r.stdOut
+}
+
+// stderr is the Output for stderr, which is created with no
+// limit if the Task isn't running.
+func (r *results) stderr() *Output {
+	if r.stdErr == nil {
+		r.stdErr = NewOutput(0, ``)
+	}
+	return r.stdErr
 }
 
 // GetReturnCode returns the return code of the executable after
@@ -89,6 +159,69 @@ func (r *results) SetStatus(status Status) {
 	r.status = status
 }
 
+// GetHistory returns the results of the previous attempts
+// at running the Task.
+// Implements Results interface.
+func (r *results) GetHistory() []Attempt {
+	return r.history
+}
+
+// SetHistory replaces the results of the previous attempts.
+// Implements Results interface.
+func (r *results) SetHistory(history []Attempt) {
+	r.history = history
+}
+
+// GetOutputs returns the values the Task captured for its
+// dependents to use.
+// Implements Results interface.
+func (r *results) GetOutputs() map[string]string {
+	return r.outputs
+}
+
+// SetOutputs replaces the captured values.
+// Implements Results interface.
+func (r *results) SetOutputs(outputs map[string]string) {
+	r.outputs = outputs
+}
+
+// GetStarted returns when the Task started running.
+// Implements Results interface.
+func (r *results) GetStarted() time.Time {
+	return r.started
+}
+
+// SetStarted replaces when the Task started running.
+// Implements Results interface.
+func (r *results) SetStarted(started time.Time) {
+	r.started = started
+}
+
+// GetFinished returns when the Task finished running.
+// Implements Results interface.
+func (r *results) GetFinished() time.Time {
+	return r.finished
+}
+
+// SetFinished replaces when the Task finished running.
+// Implements Results interface.
+func (r *results) SetFinished(finished time.Time) {
+	r.finished = finished
+}
+
+// GetFailures returns the reasons the current attempt at
+// running the Task failed.
+// Implements Results interface.
+func (r *results) GetFailures() []Failure {
+	return r.failures
+}
+
+// SetFailures replaces the reasons the Task failed.
+// Implements Results interface.
+func (r *results) SetFailures(failures []Failure) {
+	r.failures = failures
+}
+
 // ResultsProxy implements the Results interface, but allows only
 // mutex-moderated access to the underlying data. Direct access
 // can be achieved via the ResultsProxy.Atomic() method, which
@@ -118,7 +251,15 @@ func (r *ResultsProxy) Atomic(cb func(Results)) {
 func (r *ResultsProxy) GetStdOut() string {
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
-	return r.results.stdOut
+	return r.results.stdOut.String()
+}
+
+// GetStdOutLinesSince returns the lines printed to stdout after
+// the one with the given Number that are still in memory.
+func (r *ResultsProxy) GetStdOutLinesSince(number int) []Line {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.results.stdOut.LinesSince(number)
 }
 
 // SetStdOut replaces the stored text of stdout.
@@ -130,12 +271,9 @@ func (r *ResultsProxy) SetStdOut(stdOut string) {
 // AppendStdOut appends the string to the existing value
 // in stdout atomically.
 func (r *ResultsProxy) AppendStdOut(addendum string) {
-	r.Atomic(func(results Results) {
-		builder := new(strings.Builder)
-		builder.WriteString(results.GetStdOut())
-		builder.WriteString(addendum)
-		results.SetStdOut(builder.String())
-	})
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.results.stdout().Write([]byte(addendum))
 }
 
 // GetStdErr returns the accumulated text printed to stderr.
@@ -143,7 +281,15 @@ func (r *ResultsProxy) AppendStdOut(addendum string) {
 func (r *ResultsProxy) GetStdErr() string {
 	r.mtx.RLock()
 	defer r.mtx.RUnlock()
-	return r.results.stdErr
+	return r.results.stdErr.String()
+}
+
+// GetStdErrLinesSince returns the lines printed to stderr after
+// the one with the given Number that are still in memory.
+func (r *ResultsProxy) GetStdErrLinesSince(number int) []Line {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.results.stdErr.LinesSince(number)
 }
 
 // SetStdErr replaces the store text of stderr.
@@ -155,12 +301,31 @@ func (r *ResultsProxy) SetStdErr(stdErr string) {
 // AppendStdErr appends the string to the existing value
 // in stderr atomically.
 func (r *ResultsProxy) AppendStdErr(addendum string) {
-	r.Atomic(func(results Results) {
-		builder := new(strings.Builder)
-		builder.WriteString(results.GetStdErr())
-		builder.WriteString(addendum)
-		results.SetStdErr(builder.String())
-	})
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.results.stderr().Write([]byte(addendum))
+}
+
+// SetOutputStreams replaces where stdout and stderr are stored,
+// before the Task is run, so that they can have a limit.
+func (r *ResultsProxy) SetOutputStreams(stdOut, stdErr *Output) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.results.stdOut, r.results.stdErr = stdOut, stdErr
+}
+
+// FlushOutput ends the last lines of stdout and stderr once the
+// command has finished. It returns the first error spilling
+// either of them to disk, if there was one.
+func (r *ResultsProxy) FlushOutput() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.results.stdout().Flush()
+	r.results.stderr().Flush()
+	if err := r.results.stdOut.Err(); err != nil {
+		return err
+	}
+	return r.results.stdErr.Err()
 }
 
 // GetReturnCode returns the return code of the executable after
@@ -192,12 +357,131 @@ func (r *ResultsProxy) SetStatus(status Status) {
 	r.Atomic(func(results Results) { results.SetStatus(status) })
 }
 
-// Sets the status to StatusSuccess, but only if it hasn't
-// already been set to StatusFailed. Works atomically.
-func (r *ResultsProxy) SetSuccess() {
+// GetHistory returns the results of the previous attempts
+// at running the Task.
+// Implements Results interface.
+func (r *ResultsProxy) GetHistory() []Attempt {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	history := make([]Attempt, len(r.results.history))
+	copy(history, r.results.history)
+	return history
+}
+
+// SetHistory replaces the results of the previous attempts.
+// Implements Results interface.
+func (r *ResultsProxy) SetHistory(history []Attempt) {
+	r.Atomic(func(results Results) { results.SetHistory(history) })
+}
+
+// GetOutputs returns the values the Task captured for its
+// dependents to use.
+// Implements Results interface.
+func (r *ResultsProxy) GetOutputs() map[string]string {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	outputs := make(map[string]string, len(r.results.outputs))
+	for key, value := range r.results.outputs {
+		outputs[key] = value
+	}
+	return outputs
+}
+
+// SetOutputs replaces the captured values.
+// Implements Results interface.
+func (r *ResultsProxy) SetOutputs(outputs map[string]string) {
+	r.Atomic(func(results Results) { results.SetOutputs(outputs) })
+}
+
+// GetStarted returns when the Task started running.
+// Implements Results interface.
+func (r *ResultsProxy) GetStarted() time.Time {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.results.started
+}
+
+// SetStarted replaces when the Task started running.
+// Implements Results interface.
+func (r *ResultsProxy) SetStarted(started time.Time) {
+	r.Atomic(func(results Results) { results.SetStarted(started) })
+}
+
+// GetFinished returns when the Task finished running.
+// Implements Results interface.
+func (r *ResultsProxy) GetFinished() time.Time {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.results.finished
+}
+
+// SetFinished replaces when the Task finished running.
+// Implements Results interface.
+func (r *ResultsProxy) SetFinished(finished time.Time) {
+	r.Atomic(func(results Results) { results.SetFinished(finished) })
+}
+
+// GetElapsed returns how long the Task has been running for, as of
+// now, or how long it ran for if it's finished. It's zero if the
+// Task hasn't run. Works atomically.
+func (r *ResultsProxy) GetElapsed(now time.Time) time.Duration {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	started, finished := r.results.started, r.results.finished
+	if started.IsZero() {
+		return 0
+	}
+	if finished.IsZero() {
+		finished = now
+	}
+	return finished.Sub(started)
+}
+
+// GetFailures returns the reasons the current attempt at
+// running the Task failed.
+// Implements Results interface.
+func (r *ResultsProxy) GetFailures() []Failure {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	failures := make([]Failure, len(r.results.failures))
+	copy(failures, r.results.failures)
+	return failures
+}
+
+// SetFailures replaces the reasons the Task failed.
+// Implements Results interface.
+func (r *ResultsProxy) SetFailures(failures []Failure) {
+	r.Atomic(func(results Results) { results.SetFailures(failures) })
+}
+
+// AddFailure adds a reason the Task failed atomically.
+func (r *ResultsProxy) AddFailure(failure Failure) {
+	r.Atomic(func(results Results) {
+		results.SetFailures(append(results.GetFailures(), failure))
+	})
+}
+
+// Reset clears the results, as if the Task had never run, so
+// that it can be run again. Works atomically.
+func (r *ResultsProxy) Reset() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.results = new(results)
+}
+
+// NextAttempt moves the output and return code of the current
+// attempt into the history, and clears them for the next
+// attempt. Works atomically.
+func (r *ResultsProxy) NextAttempt() {
 	r.Atomic(func(results Results) {
-		if results.GetStatus() != StatusFailed {
-			results.SetStatus(StatusSucceeded)
-		}
+		results.SetHistory(append(results.GetHistory(), Attempt{
+			StdOut:     results.GetStdOut(),
+			StdErr:     results.GetStdErr(),
+			ReturnCode: results.GetReturnCode(),
+		}))
+		results.SetStdOut(``)
+		results.SetStdErr(``)
+		results.SetReturnCode(0)
+		results.SetFailures(nil)
 	})
 }
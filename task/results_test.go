@@ -3,6 +3,7 @@ package task
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestAtomic(t *testing.T) {
@@ -40,16 +41,40 @@ func TestAppendStdErr(t *testing.T) {
 	}
 }
 
-func TestSetSuccess(t *testing.T) {
+func TestNextAttempt(t *testing.T) {
 	r := NewResultsProxy()
-	r.SetStatus(StatusNotRun)
-	r.SetSuccess()
-	if actual := r.GetStatus(); actual != StatusSucceeded {
-		t.Fatalf(`expected status to be success; was %v`, actual)
-	}
-	r.SetStatus(StatusFailed)
-	r.SetSuccess()
-	if actual := r.GetStatus(); actual == StatusSucceeded {
-		t.Fatalf(`expected status not to be success; was %v`, actual)
+	r.SetStdOut(`out`)
+	r.SetStdErr(`err`)
+	r.SetReturnCode(2)
+	r.NextAttempt()
+	if actual := r.GetStdOut(); actual != `` {
+		t.Fatalf(`expected stdout to be cleared; was %q`, actual)
+	}
+	if actual := r.GetStdErr(); actual != `` {
+		t.Fatalf(`expected stderr to be cleared; was %q`, actual)
+	}
+	history := r.GetHistory()
+	if actual := len(history); actual != 1 {
+		t.Fatalf(`expected one attempt in the history; found %d`, actual)
+	}
+	expected := Attempt{StdOut: `out`, StdErr: `err`, ReturnCode: 2}
+	if actual := history[0]; actual != expected {
+		t.Fatalf(`expected attempt to be %+v; was %+v`, expected, actual)
+	}
+}
+
+func TestGetElapsed(t *testing.T) {
+	r := NewResultsProxy()
+	started := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	if actual := r.GetElapsed(started); actual != 0 {
+		t.Fatalf(`expected no time to have elapsed before starting; was %v`, actual)
+	}
+	r.SetStarted(started)
+	if actual := r.GetElapsed(started.Add(3 * time.Second)); actual != 3*time.Second {
+		t.Fatalf(`expected 3s to have elapsed while running; was %v`, actual)
+	}
+	r.SetFinished(started.Add(2 * time.Second))
+	if actual := r.GetElapsed(started.Add(time.Minute)); actual != 2*time.Second {
+		t.Fatalf(`expected 2s to have elapsed once finished; was %v`, actual)
 	}
 }
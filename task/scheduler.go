@@ -0,0 +1,407 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// runner runs a single Task. RunAll uses Task.Run; the tests
+// swap in runners that don't start any processes.
+type runner func(ctx context.Context, t *Task, handler func(*Task)) error
+
+func runTask(ctx context.Context, t *Task, handler func(*Task)) error {
+	return t.Run(ctx, handler)
+}
+
+// dependent is a Task that depends on another, and whether it
+// needs that one to succeed (positive) or to fail.
+type dependent struct {
+	task     *Task
+	positive bool
+}
+
+// finished is what a Task's goroutine reports when it's done.
+type finished struct {
+	task *Task
+	err  error
+}
+
+// scheduler runs a TaskList by reacting to Tasks finishing,
+// rather than rescanning the whole list each time one does. It
+// works out once which Tasks depend on which, and how many of
+// each Task's dependencies haven't finished yet, so that each
+// finished Task only has to look at its own dependents.
+type scheduler struct {
+	list    TaskList
+	opts    RunOptions
+	handler func(*Task)
+	run     runner
+
+	// dependents are the Tasks that depend on each Task, once
+	// for each time they name it.
+	dependents map[string][]dependent
+	// waitingOn is how many of each Task's dependencies haven't
+	// finished yet.
+	waitingOn map[string]int
+	// resolved are the Tasks whose dependents know they've
+	// finished.
+	resolved map[string]bool
+
+	// queue is kept in the order the Tasks should start in.
+	queue     []*Task
+	estimates Estimates
+	pools     *resourcePools
+	running   int
+	done      chan finished
+	errors    []error
+
+	// state is nil unless Tasks are to be skipped when they're
+	// up to date.
+	state *state
+
+	control *control
+}
+
+func newScheduler(sl TaskList, opts RunOptions, handler func(*Task), run runner) *scheduler {
+	sc := &scheduler{
+		list:       sl,
+		opts:       opts,
+		handler:    handler,
+		run:        run,
+		dependents: make(map[string][]dependent, len(sl)),
+		waitingOn:  make(map[string]int, len(sl)),
+		resolved:   make(map[string]bool, len(sl)),
+		queue:      make([]*Task, 0, len(sl)),
+		estimates:  sl.Estimate(opts.History),
+		pools:      newResourcePools(opts.Resources),
+		// Every Task reports once (unless it's run again), so
+		// with room for all of them, reporting hardly blocks.
+		done: make(chan finished, len(sl)),
+		control: &control{
+			events:  make(chan []*Task),
+			stopped: make(chan struct{}),
+		},
+	}
+	for _, task := range sl.sorted() {
+		task.outputDir = opts.OutputDir
+		sc.waitingOn[task.Name] = len(task.Dependencies)
+		for _, dep := range task.Dependencies {
+			key, positive := parseDependencyName(dep)
+			sc.dependents[key] = append(sc.dependents[key], dependent{task, positive})
+		}
+	}
+	return sc
+}
+
+// dependencyMet tells whether a dependency that has finished with
+// the given Status lets the Task that depends on it run. Negated
+// dependencies are met when the dependency fails.
+func dependencyMet(status Status, positive bool) bool {
+	if positive {
+		return status.IsSuccess()
+	}
+	return status.IsFailure()
+}
+
+// resolve lets the dependents of a Task that has finished know
+// about it. Dependents whose dependencies are now all met are
+// queued, and ones that can no longer run are marked
+// StatusDependenciesNotMet, which in turn resolves their own
+// dependents.
+func (sc *scheduler) resolve(task *Task) {
+	resolved := []*Task{task}
+	for len(resolved) > 0 {
+		task, resolved = resolved[0], resolved[1:]
+		sc.resolved[task.Name] = true
+		status := task.GetStatus()
+		for _, d := range sc.dependents[task.Name] {
+			if d.task.GetStatus() != StatusNotRun {
+				continue
+			}
+			if !dependencyMet(status, d.positive) {
+				d.task.results.SetStatus(StatusDependenciesNotMet)
+				sc.handler(d.task)
+				resolved = append(resolved, d.task)
+				continue
+			}
+			sc.waitingOn[d.task.Name]--
+			if sc.waitingOn[d.task.Name] == 0 {
+				sc.enqueue(d.task)
+			}
+		}
+	}
+}
+
+func (sc *scheduler) enqueue(task *Task) {
+	task.results.SetStatus(StatusQueued)
+	sc.handler(task)
+	i := sort.Search(len(sc.queue), func(i int) bool {
+		return sc.estimates.before(task, sc.queue[i])
+	})
+	sc.queue = append(sc.queue, nil)
+	copy(sc.queue[i+1:], sc.queue[i:])
+	sc.queue[i] = task
+}
+
+// launch starts as many of the queued Tasks as there are free
+// slots and resources for, in order of priority.
+//
+// Tasks are given the outputs of the Tasks they depend on as
+// they're launched. Any that can't be fail without running.
+func (sc *scheduler) launch(ctx context.Context) {
+	waiting := sc.queue[:0]
+	unfilled := make([]*Task, 0)
+	for _, task := range sc.queue {
+		if sc.opts.Jobs > 0 && sc.running >= sc.opts.Jobs {
+			waiting = append(waiting, task)
+			continue
+		}
+		if !sc.pools.tryAcquire(task.Resources) {
+			waiting = append(waiting, task)
+			continue
+		}
+		started := false
+		task.results.Atomic(func(r Results) {
+			// It may have been skipped while it was queued.
+			if r.GetStatus() == StatusQueued {
+				r.SetStatus(StatusRunning)
+				started = true
+			}
+		})
+		if !started {
+			sc.pools.release(task.Resources)
+			continue
+		}
+		if err := task.fillOutputs(sc.list); err != nil {
+			sc.pools.release(task.Resources)
+			task.results.AddFailure(newFailure(FailureOutputs, `%v`, err))
+			task.results.SetStatus(StatusFailed)
+			sc.handler(task)
+			unfilled = append(unfilled, task)
+			continue
+		}
+		sc.running++
+		forced := task.takeForced()
+		go func(s *Task) {
+			cached := sc.state != nil && s.isCacheable()
+			if cached && !sc.opts.Force && !forced && s.checkUpToDate(sc.state) {
+				sc.handler(s)
+				sc.done <- finished{task: s}
+				return
+			}
+			err := sc.run(ctx, s, sc.handler)
+			if err == nil && cached {
+				if errState := s.remember(sc.state); errState != nil {
+					log.Println(errState)
+				}
+			}
+			sc.done <- finished{task: s, err: err}
+		}(task)
+	}
+	sc.queue = waiting
+	if len(unfilled) > 0 {
+		for _, task := range unfilled {
+			sc.resolve(task)
+		}
+		sc.launch(ctx)
+	}
+}
+
+// dequeue takes the Task out of the queue, if it's there.
+func (sc *scheduler) dequeue(task *Task) {
+	queue := sc.queue[:0]
+	for _, t := range sc.queue {
+		if t != task {
+			queue = append(queue, t)
+		}
+	}
+	sc.queue = queue
+}
+
+// requeue works out what to do with a Task that has been reset
+// to run again: queue it if its dependencies are already met, or
+// wait for the ones that aren't finished.
+func (sc *scheduler) requeue(task *Task) {
+	waiting := 0
+	for _, dep := range task.Dependencies {
+		key, positive := parseDependencyName(dep)
+		status := sc.list[key].GetStatus()
+		if !status.IsFinal() {
+			waiting++
+			continue
+		}
+		if !dependencyMet(status, positive) {
+			task.results.SetStatus(StatusDependenciesNotMet)
+			sc.handler(task)
+			sc.resolve(task)
+			return
+		}
+	}
+	sc.waitingOn[task.Name] = waiting
+	if waiting == 0 {
+		sc.enqueue(task)
+	}
+}
+
+// changed deals with Tasks whose Statuses were changed from
+// outside while the Tasks were running: ones that were skipped
+// (with TaskList.Skip), or reset to run again (with
+// TaskList.Rerun).
+func (sc *scheduler) changed(tasks []*Task) {
+	for _, task := range tasks {
+		sc.handler(task)
+		if task.GetStatus() == StatusCancelled {
+			sc.dequeue(task)
+			sc.resolve(task)
+		}
+	}
+	reset := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		if task.GetStatus() == StatusNotRun {
+			reset[task.Name] = true
+		}
+	}
+	// The dependents of the reset Tasks that weren't reset with
+	// them counted them as finished. Now they have to wait for
+	// them again, even the ones that were already queued.
+	for _, task := range tasks {
+		if !reset[task.Name] || !sc.resolved[task.Name] {
+			continue
+		}
+		sc.resolved[task.Name] = false
+		for _, d := range sc.dependents[task.Name] {
+			if reset[d.task.Name] {
+				continue
+			}
+			unqueued := false
+			d.task.results.Atomic(func(r Results) {
+				if r.GetStatus() == StatusQueued {
+					r.SetStatus(StatusNotRun)
+					unqueued = true
+				}
+			})
+			if unqueued {
+				sc.dequeue(d.task)
+				sc.handler(d.task)
+				reset[d.task.Name] = true
+				tasks = append(tasks, d.task)
+			} else if d.task.GetStatus() == StatusNotRun {
+				sc.waitingOn[d.task.Name]++
+			}
+		}
+	}
+	// None of the reset Tasks are launched until they've all been
+	// requeued, so they wait on each other. Any that were marked
+	// StatusDependenciesNotMet along the way are already dealt
+	// with.
+	for _, task := range tasks {
+		if task.GetStatus() == StatusNotRun {
+			sc.requeue(task)
+		}
+	}
+}
+
+// cancel marks every Task that hasn't started StatusCancelled.
+func (sc *scheduler) cancel() {
+	sc.queue = sc.queue[:0]
+	for _, task := range sc.list.sorted() {
+		if status := task.GetStatus(); status == StatusNotRun || status == StatusQueued {
+			task.results.SetStatus(StatusCancelled)
+			sc.handler(task)
+		}
+	}
+}
+
+// start queues the Tasks with no dependencies, and resolves the
+// dependents of any Tasks that were already finished before the
+// run started.
+func (sc *scheduler) start() {
+	for _, task := range sc.list.sorted() {
+		if task.GetStatus() == StatusNotRun && sc.waitingOn[task.Name] == 0 {
+			sc.enqueue(task)
+		}
+	}
+	for _, task := range sc.list.sorted() {
+		if task.GetStatus().IsFinal() {
+			sc.resolve(task)
+		}
+	}
+}
+
+func (sc *scheduler) runAll(ctx context.Context) error {
+	// Set to nil once the cancellation has been dealt with.
+	cancelled := ctx.Done()
+	if err := ctx.Err(); err != nil {
+		sc.cancel()
+		return err
+	}
+	for _, task := range sc.list {
+		task.setControl(sc.control)
+	}
+	defer func() {
+		close(sc.control.stopped)
+		for _, task := range sc.list {
+			task.setControl(nil)
+		}
+	}()
+
+	sc.start()
+	sc.launch(ctx)
+	for sc.running > 0 {
+		select {
+		case tasks := <-sc.control.events:
+			if ctx.Err() != nil {
+				// Too late to run anything again.
+				sc.cancel()
+			} else {
+				sc.changed(tasks)
+			}
+		case f := <-sc.done:
+			sc.running--
+			sc.pools.release(f.task.Resources)
+			if f.err != nil {
+				sc.errors = append(sc.errors, fmt.Errorf(`error running task %q: %w`, f.task.Name, f.err))
+			} else if f.task.GetStatus().IsFinal() && !sc.resolved[f.task.Name] {
+				// It may have been reset to run again before
+				// this was heard, in which case it's resolved
+				// when it finishes again.
+				sc.resolve(f.task)
+			}
+		case <-cancelled:
+			cancelled = nil
+			sc.cancel()
+		}
+		// Once something has gone wrong, let the running Tasks
+		// finish, but don't start any more.
+		if len(sc.errors) == 0 {
+			sc.launch(ctx)
+		}
+	}
+
+	if len(sc.errors) > 0 {
+		for _, err := range sc.errors {
+			log.Println(err)
+		}
+		return fmt.Errorf(`received one or more errors running tasks, the last of which is %w`, sc.errors[len(sc.errors)-1])
+	}
+	if err := ctx.Err(); err != nil {
+		sc.cancel()
+		return err
+	}
+	if !sc.list.IsFinished() {
+		// Nothing's running, but some Tasks never got to run.
+		// That means a dependency loop.
+		taskdump := new(strings.Builder)
+		for _, task := range sc.list.sorted() {
+			fmt.Fprintf(taskdump, "%s: %s\n", task.Name, task.GetStatus())
+			for _, dep := range task.Dependencies {
+				fmt.Fprintf(taskdump, "\t- %s\n", dep)
+			}
+		}
+		return fmt.Errorf("deadlock detected: not finished, but not ready to run\n%s", taskdump.String())
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package task
+
+// Settings are the top-level entries in the task file that aren't
+// Tasks themselves, like defaults for every Task. Their keys are
+// reserved, and can't be used as Task names.
+type Settings struct {
+	// Timeout is the Timeout for every Task that doesn't
+	// specify its own.
+	Timeout Duration `yaml:"timeout"`
+
+	// Jobs is the most Tasks that may run at the same time.
+	// Zero means there's no limit.
+	Jobs int `yaml:"jobs"`
+
+	// Resources are the sizes of the resource pools named in
+	// the Tasks' Resources. Pools that aren't listed have a
+	// size of one.
+	Resources map[string]int `yaml:"resources"`
+
+	// WorkingDirectory is the WorkingDirectory for every Task
+	// that doesn't specify its own.
+	WorkingDirectory string `yaml:"workingDirectory"`
+
+	// Shell is the Shell for every Task that doesn't specify
+	// its own.
+	Shell string `yaml:"shell"`
+
+	// Vars are the variables that Tasks can use as ${NAME}.
+	Vars map[string]string `yaml:"vars"`
+
+	// OutputLimit is the OutputLimit for every Task that
+	// doesn't specify its own.
+	OutputLimit int `yaml:"outputLimit"`
+}
+
+// TaskFile is everything in a task file: the top-level Settings
+// and the TaskList.
+type TaskFile struct {
+	Settings
+	Tasks TaskList
+
+	// Overrides are values for Vars, like the ones given on
+	// the command line, that take precedence over the ones in
+	// the task file and the environment. Set them before
+	// unmarshaling.
+	Overrides map[string]string `yaml:"-"`
+}
+
+// UnmarshalYAML reads the Settings and the Tasks from the same
+// top-level map.
+func (tf *TaskFile) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&tf.Settings); err != nil {
+		return err
+	}
+	tf.Tasks = make(TaskList)
+	return tf.Tasks.parse(unmarshal, tf.Overrides)
+}
+
+// RunOptions are the options for TaskList.RunAll given by the
+// Settings.
+func (st *Settings) RunOptions() RunOptions {
+	return RunOptions{Jobs: st.Jobs, Resources: st.Resources}
+}
+
+// apply fills in the Task's fields that it left blank with the
+// defaults from the Settings.
+func (st *Settings) apply(task *Task) {
+	if task.Timeout == 0 {
+		task.Timeout = st.Timeout
+	}
+	if task.WorkingDirectory == `` {
+		task.WorkingDirectory = st.WorkingDirectory
+	}
+	if task.Shell == `` {
+		task.Shell = st.Shell
+	}
+	if task.OutputLimit == 0 {
+		task.OutputLimit = st.OutputLimit
+	}
+}
@@ -0,0 +1,31 @@
+package task
+
+import (
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestTaskFileUnmarshalYAML(t *testing.T) {
+	var file TaskFile
+	err := yaml.Unmarshal([]byte(`---
+jobs: 4
+Only Task:
+  command: "true"
+`), &file)
+	if err != nil {
+		t.Fatalf(`could not unmarshal task file: %v`, err)
+	}
+	if actual := file.Jobs; actual != 4 {
+		t.Fatalf(`expected jobs to be 4; was %d`, actual)
+	}
+	if actual := file.RunOptions().Jobs; actual != 4 {
+		t.Fatalf(`expected RunOptions().Jobs to be 4; was %d`, actual)
+	}
+	if actual := len(file.Tasks); actual != 1 {
+		t.Fatalf(`expected 1 task; found %d`, actual)
+	}
+	if _, ok := file.Tasks[`Only Task`]; !ok {
+		t.Fatalf(`expected to find "Only Task"; didn't`)
+	}
+}
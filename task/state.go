@@ -0,0 +1,181 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/Unquabain/fac/util"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// stateFile is the name of the file in the state directory that
+// keeps track of what each Task last ran with.
+const stateFile = `state.yaml`
+
+// fingerprint is what a Task last succeeded with.
+type fingerprint struct {
+	// Hash covers the Task's command line, environment and
+	// working directory, and the names and contents of its
+	// Sources and Generates files.
+	Hash string `yaml:"hash"`
+
+	// Outputs are the Task's outputs from that run, for the
+	// Tasks that depend on it.
+	Outputs map[string]string `yaml:"outputs,omitempty"`
+}
+
+// state remembers the fingerprints of the Tasks between runs, in
+// a directory of its own.
+type state struct {
+	dir          string
+	fingerprints map[string]fingerprint
+	mtx          sync.Mutex
+}
+
+// loadState reads the fingerprints from the state directory. It
+// isn't an error for there not to be any yet.
+func loadState(dir string) (*state, error) {
+	st := &state{dir: dir, fingerprints: make(map[string]fingerprint)}
+	buff, err := ioutil.ReadFile(filepath.Join(dir, stateFile))
+	if os.IsNotExist(err) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf(`couldn't read state: %w`, err)
+	}
+	if err := yaml.Unmarshal(buff, &st.fingerprints); err != nil {
+		return nil, fmt.Errorf(`couldn't parse state in %q: %w`, dir, err)
+	}
+	return st, nil
+}
+
+func (st *state) get(name string) (fingerprint, bool) {
+	st.mtx.Lock()
+	defer st.mtx.Unlock()
+	fp, ok := st.fingerprints[name]
+	return fp, ok
+}
+
+// set records the Task's fingerprint, or forgets it if fp is nil,
+// and writes the state directory.
+func (st *state) set(name string, fp *fingerprint) error {
+	st.mtx.Lock()
+	defer st.mtx.Unlock()
+	if fp == nil {
+		delete(st.fingerprints, name)
+	} else {
+		st.fingerprints[name] = *fp
+	}
+	buff, err := yaml.Marshal(st.fingerprints)
+	if err != nil {
+		return fmt.Errorf(`couldn't serialize state: %w`, err)
+	}
+	if err := os.MkdirAll(st.dir, 0755); err != nil {
+		return fmt.Errorf(`couldn't create state directory: %w`, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(st.dir, stateFile), buff, 0644); err != nil {
+		return fmt.Errorf(`couldn't write state: %w`, err)
+	}
+	return nil
+}
+
+// isCacheable tells whether the Task lists files that say when
+// it needs to run again.
+func (s *Task) isCacheable() bool {
+	return len(s.Sources) > 0 || len(s.Generates) > 0
+}
+
+// glob finds the files matching a Sources or Generates pattern,
+// relative to the WorkingDirectory.
+func (s *Task) glob(pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(s.WorkingDirectory, pattern)
+	}
+	return util.Glob(pattern)
+}
+
+// hash works out the Hash of the Task's fingerprint as things
+// stand. It also reports whether every Generates pattern matched
+// at least one file.
+func (s *Task) hash() (string, bool, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%q\n%q\n", s.CommandLine(), s.WorkingDirectory)
+	keys := make([]string, 0, len(s.Environment))
+	for key := range s.Environment {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(h, "%q=%q\n", key, s.Environment[key])
+	}
+	generated := true
+	for i, patterns := range [][]string{s.Sources, s.Generates} {
+		fmt.Fprintln(h, `--`)
+		for _, pattern := range patterns {
+			files, err := s.glob(pattern)
+			if err != nil {
+				return ``, false, err
+			}
+			if i == 1 && len(files) == 0 {
+				generated = false
+			}
+			sort.Strings(files)
+			for _, file := range files {
+				if err := hashFile(h, file); err != nil {
+					return ``, false, err
+				}
+			}
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), generated, nil
+}
+
+func hashFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintf(w, "%q\n", path)
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// checkUpToDate marks the Task StatusUpToDate, with the outputs
+// it had last time, if it ran successfully before and none of its
+// Sources or Generates files have changed since, and they're all
+// still there.
+func (s *Task) checkUpToDate(st *state) bool {
+	last, ok := st.get(s.Name)
+	if !ok {
+		return false
+	}
+	hash, generated, err := s.hash()
+	if err != nil || !generated || hash != last.Hash {
+		return false
+	}
+	s.results.SetOutputs(last.Outputs)
+	s.results.SetStatus(StatusUpToDate)
+	return true
+}
+
+// remember records the Task's fingerprint if it succeeded, so
+// that it can be skipped next time if nothing changes, or forgets
+// it if it didn't.
+func (s *Task) remember(st *state) error {
+	if s.GetStatus() != StatusSucceeded {
+		return st.set(s.Name, nil)
+	}
+	hash, _, err := s.hash()
+	if err != nil {
+		return fmt.Errorf(`couldn't fingerprint %q: %w`, s.Name, err)
+	}
+	return st.set(s.Name, &fingerprint{Hash: hash, Outputs: s.GetOutputs()})
+}
@@ -0,0 +1,60 @@
+package task
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpToDate(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, `in.txt`)
+	if err := ioutil.WriteFile(source, []byte(`one`), 0644); err != nil {
+		t.Fatalf(`could not set up test files: %v`, err)
+	}
+	opts := RunOptions{StateDir: filepath.Join(dir, `.fac`)}
+	run := func(expected Status) {
+		list, err := getTaskListFromYaml(`---
+workingDirectory: ` + dir + `
+Copy:
+  run: cp in.txt out.txt && echo "copied $(cat in.txt)"
+  sources: [in.txt]
+  generates: ["*.txt"]
+  outputs:
+    pattern: copied (?P<word>\w+)
+`)
+		if err != nil {
+			t.Fatalf(`could not test up to date checks: %v`, err)
+		}
+		if err := list.RunAll(context.Background(), opts, func(*Task) {}); err != nil {
+			t.Fatalf(`could not run tasks: %v`, err)
+		}
+		task := list[`Copy`]
+		if actual := task.GetStatus(); actual != expected {
+			t.Fatalf(`expected "Copy" to be %v; was %v`, expected, actual)
+		}
+		buff, _ := ioutil.ReadFile(source)
+		if actual := task.GetOutputs()[`word`]; actual != string(buff) {
+			t.Fatalf(`expected "Copy" to output %q; output %q`, buff, actual)
+		}
+	}
+
+	run(StatusSucceeded)
+	run(StatusUpToDate)
+
+	if err := ioutil.WriteFile(source, []byte(`two`), 0644); err != nil {
+		t.Fatalf(`could not change test files: %v`, err)
+	}
+	run(StatusSucceeded)
+	run(StatusUpToDate)
+
+	if err := os.Remove(filepath.Join(dir, `out.txt`)); err != nil {
+		t.Fatalf(`could not remove generated file: %v`, err)
+	}
+	run(StatusSucceeded)
+
+	opts.Force = true
+	run(StatusSucceeded)
+}
@@ -1,5 +1,7 @@
 package task
 
+import "fmt"
+
 // Status is an enum for the lifecycle of a Task.
 type Status uint32
 
@@ -9,8 +11,25 @@ const (
 	StatusRunning
 	StatusFailed
 	StatusSucceeded
+	StatusTimedOut
+	StatusQueued
+	StatusCancelled
+	StatusUpToDate
 )
 
+// statuses is every Status, for looking them up by name.
+var statuses = []Status{
+	StatusNotRun,
+	StatusDependenciesNotMet,
+	StatusRunning,
+	StatusFailed,
+	StatusSucceeded,
+	StatusTimedOut,
+	StatusQueued,
+	StatusCancelled,
+	StatusUpToDate,
+}
+
 func (s Status) String() string {
 	switch s {
 	case StatusNotRun:
@@ -23,6 +42,14 @@ func (s Status) String() string {
 		return `Failed`
 	case StatusSucceeded:
 		return `Succeeded`
+	case StatusTimedOut:
+		return `Timed Out`
+	case StatusQueued:
+		return `Queued`
+	case StatusCancelled:
+		return `Cancelled`
+	case StatusUpToDate:
+		return `Up To Date`
 	default:
 		return `Unknown`
 	}
@@ -43,7 +70,63 @@ func (s Status) IsOK() bool {
 		return false
 	case StatusSucceeded:
 		return true
+	case StatusTimedOut:
+		return false
+	case StatusQueued:
+		return true
+	case StatusCancelled:
+		return false
+	case StatusUpToDate:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsSuccess tells whether the Task's work is done: either it
+// ran and succeeded, or it didn't need to run because nothing
+// had changed since it last did. Tasks that depend on it may
+// run.
+func (s Status) IsSuccess() bool {
+	return s == StatusSucceeded || s == StatusUpToDate
+}
+
+// IsFinal tells whether the Task is done, one way
+// or another, and its status won't change again.
+func (s Status) IsFinal() bool {
+	switch s {
+	case StatusDependenciesNotMet, StatusFailed, StatusSucceeded, StatusTimedOut, StatusCancelled, StatusUpToDate:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsFailure tells whether the Task ran and failed, in
+// whichever way. Tasks that depend on it negatively
+// ("!" or "-") may run.
+func (s Status) IsFailure() bool {
+	switch s {
+	case StatusFailed, StatusTimedOut:
+		return true
 	default:
 		return false
 	}
 }
+
+// MarshalText writes the Status by name, so that records of
+// a run are readable.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads a Status written by MarshalText.
+func (s *Status) UnmarshalText(text []byte) error {
+	for _, status := range statuses {
+		if status.String() == string(text) {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf(`unknown status: %q`, text)
+}
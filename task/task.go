@@ -1,12 +1,15 @@
 package task
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
-	"regexp"
+	"strings"
 	"sync"
+	"time"
 )
 
 // Task is the taskification of a task to run
@@ -27,10 +30,24 @@ type Task struct {
 	// Args are arguments to pass to Command
 	Args []string
 
+	// Script is a shell script to run instead of Command
+	// and Args. It may be several lines long.
+	Script string `yaml:"run"`
+
+	// Shell is the shell that runs Script, with any options
+	// it needs, like "bash -eo pipefail". The script is
+	// passed to it after "-c". Defaults to DefaultShell.
+	Shell string `yaml:"shell"`
+
 	// Environment is any shell environment variables
 	// that Command will need.
 	Environment map[string]string
 
+	// WorkingDirectory is the directory to run Command in.
+	// Relative paths are relative to the task file. If it's
+	// blank, Command runs in fac's working directory.
+	WorkingDirectory string `yaml:"workingDirectory"`
+
 	// ExpectedReturnCode is the return code that
 	// Command should result in to consider this Task
 	// successful.
@@ -46,12 +63,81 @@ type Task struct {
 	// this Task run as successful.
 	ExpectedStdErrRegex string `yaml:"expectedStdErrRegex"`
 
+	// Outputs says where to find the values this Task passes
+	// on to the Tasks that depend on it, which they can use in
+	// their Command, Args and Environment as
+	// {{ .Tasks.Name.outputs.key }}.
+	Outputs OutputSpec `yaml:"outputs"`
+
+	// Resources are the names of resource pools this Task
+	// needs a share of while it runs. It won't start until
+	// there's one free in each pool.
+	Resources []string
+
+	// Sources are glob patterns for the files Command reads,
+	// relative to the WorkingDirectory. "**" matches any
+	// number of directories. If a Task has Sources or
+	// Generates, and none of those files have changed since
+	// it last succeeded, it's marked StatusUpToDate instead of
+	// being run.
+	Sources []string `yaml:"sources"`
+
+	// Generates are glob patterns for the files Command
+	// writes. The Task is only up to date if each of them
+	// matches a file, and those haven't changed either.
+	Generates []string `yaml:"generates"`
+
+	// Timeout is how long Command may run before it is
+	// stopped and the Task is marked as timed out. Zero
+	// means it may run forever.
+	Timeout Duration `yaml:"timeout"`
+
+	// Retries is how many more times Command will be run
+	// if it fails before the Task is considered failed.
+	Retries int `yaml:"retries"`
+
+	// RetryDelay is how long to wait after a failed
+	// attempt before trying again.
+	RetryDelay Duration `yaml:"retryDelay"`
+
+	// RetryBackoff multiplies RetryDelay after every
+	// failed attempt, so that the waits grow longer.
+	// Zero leaves RetryDelay the same every time.
+	RetryBackoff float64 `yaml:"retryBackoff"`
+
+	// OutputLimit is how many bytes of each of STDOUT and
+	// STDERR are kept in memory. The oldest lines are dropped
+	// to stay under it. Zero means DefaultOutputLimit, and a
+	// negative number means there's no limit.
+	OutputLimit int `yaml:"outputLimit"`
+
+	// Priority decides which Tasks start first when more are
+	// ready to run than there are free jobs or resources:
+	// higher first. Among Tasks with the same Priority, the
+	// ones with the longest chain of Tasks left after them go
+	// first.
+	Priority int `yaml:"priority"`
+
 	// Order is set in the YAML parser for consistency
 	// in the interface. (Otherwise, the list reshuffles
 	// whenever it updates.)
 	Order int `yaml:"-"`
 
-	results *ResultsProxy
+	results   *ResultsProxy
+	templates *templates
+
+	// outputDir is where the lines dropped from memory are
+	// spilled, if anywhere.
+	outputDir string
+
+	// mtx guards cancel and control, which are set while
+	// the Task is being run, and forced.
+	mtx     sync.Mutex
+	cancel  context.CancelFunc
+	control *control
+	// forced is set when the Task has been reset to run again,
+	// so that it runs even if it's up to date.
+	forced bool
 }
 
 // GetStatus gets the current status atomically.
@@ -64,39 +150,74 @@ func (s *Task) GetStdOut() string {
 	return s.results.GetStdOut()
 }
 
+// GetStdOutLinesSince gets the lines of STDOUT still in memory
+// that come after the one with the given Number atomically.
+func (s *Task) GetStdOutLinesSince(number int) []Line {
+	return s.results.GetStdOutLinesSince(number)
+}
+
+// GetStdErrLinesSince gets the lines of STDERR still in memory
+// that come after the one with the given Number atomically.
+func (s *Task) GetStdErrLinesSince(number int) []Line {
+	return s.results.GetStdErrLinesSince(number)
+}
+
 // GetStdErr gets the accumulated STDERR text atomically.
 func (s *Task) GetStdErr() string {
 	return s.results.GetStdErr()
 }
 
-func (s *Task) evaluateSuccess() {
-	if s.results.GetStatus() == StatusFailed {
-		return
-	}
-	if s.results.GetReturnCode() != s.ExpectedReturnCode {
-		s.results.SetStatus(StatusFailed)
-		return
-	}
-	if s.ExpectedStdOutRegex != `` {
-		pattern := regexp.MustCompile(s.ExpectedStdOutRegex)
-		if !pattern.MatchString(s.results.GetStdOut()) {
-			s.results.SetStatus(StatusFailed)
-			return
-		}
+// GetStarted gets when the Task last started running atomically.
+// It's zero if the Task hasn't run.
+func (s *Task) GetStarted() time.Time {
+	return s.results.GetStarted()
+}
+
+// GetFinished gets when the Task last finished running
+// atomically. It's zero if the Task hasn't run, or is still
+// running.
+func (s *Task) GetFinished() time.Time {
+	return s.results.GetFinished()
+}
+
+// GetElapsed gets how long the Task has been running for as of
+// now, including any retries, or how long it ran for if it's
+// finished. It's zero if the Task hasn't run.
+func (s *Task) GetElapsed(now time.Time) time.Duration {
+	return s.results.GetElapsed(now)
+}
+
+// GetAttempt gets which attempt at running Command the Task is
+// on, starting from 1.
+func (s *Task) GetAttempt() int {
+	return len(s.results.GetHistory()) + 1
+}
+
+// GetHistory gets the results of the previous, failed attempts
+// at running Command, oldest first.
+func (s *Task) GetHistory() []Attempt {
+	return s.results.GetHistory()
+}
+
+// DefaultShell is the Shell that runs Scripts when neither the
+// Task nor the task file names one.
+const DefaultShell = `/bin/sh -e`
+
+// CommandLine is the command and arguments that the Task runs.
+// For a Script, that's the Shell, "-c" and the Script.
+func (s *Task) CommandLine() []string {
+	if s.Script == `` {
+		return append([]string{s.Command}, s.Args...)
 	}
-	if s.ExpectedStdErrRegex != `` {
-		pattern := regexp.MustCompile(s.ExpectedStdErrRegex)
-		if !pattern.MatchString(s.results.GetStdErr()) {
-			s.results.SetStatus(StatusFailed)
-			return
-		}
+	shell := strings.Fields(s.Shell)
+	if len(shell) == 0 {
+		shell = strings.Fields(DefaultShell)
 	}
-	s.results.SetSuccess()
+	return append(shell, `-c`, s.Script)
 }
 
 func (s *Task) env() []string {
-	env := make([]string, 0, len(os.Environ())+len(s.Environment))
-	copy(env, os.Environ())
+	env := append([]string{}, os.Environ()...)
 	for key, val := range s.Environment {
 		env = append(env, fmt.Sprintf(`%s=%s`, key, val))
 	}
@@ -107,23 +228,116 @@ func (s *Task) env() []string {
 // command has finished, but updateHandler will be called
 // several times from different go routines whenever a change
 // has been made to the status of the Task.
-func (s *Task) Run(updateHandler func(*Task)) error {
-	var wg sync.WaitGroup
-	s.results.SetStatus(StatusRunning)
+//
+// If the command fails and the Task has Retries, it is run
+// again after RetryDelay (growing by RetryBackoff each time).
+// The Task stays StatusRunning until the last attempt is over.
+//
+// If ctx is cancelled, or Cancel is called, the command's whole
+// process group is stopped, no more attempts are made, and the
+// Task is marked StatusCancelled.
+func (s *Task) Run(ctx context.Context, updateHandler func(*Task)) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.setCancel(cancel)
+	defer s.setCancel(nil)
+	s.results.SetOutputStreams(s.newOutputs(1))
+	s.results.Atomic(func(r Results) {
+		r.SetStarted(time.Now())
+		r.SetFinished(time.Time{})
+		r.SetFailures(nil)
+		r.SetStatus(StatusRunning)
+	})
 	updateHandler(s)
-	cmd := exec.Command(s.Command, s.Args...)
+	delay := time.Duration(s.RetryDelay)
+	var final Status
+	for {
+		status := s.runAttempt(ctx, updateHandler)
+		if err := s.results.FlushOutput(); err != nil {
+			s.results.AppendStdErr(err.Error() + "\n")
+		}
+		updateHandler(s)
+		if status.IsOK() || status == StatusCancelled || s.GetAttempt() > s.Retries {
+			final = status
+			break
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			final = StatusCancelled
+			break
+		}
+		if s.RetryBackoff > 0 {
+			delay = time.Duration(float64(delay) * s.RetryBackoff)
+		}
+		s.results.NextAttempt()
+		s.results.SetOutputStreams(s.newOutputs(s.GetAttempt()))
+		updateHandler(s)
+	}
+	s.results.Atomic(func(r Results) {
+		r.SetFinished(time.Now())
+		r.SetStatus(final)
+	})
+	updateHandler(s)
+	return nil
+}
+
+// fail records why the current attempt failed, and returns
+// StatusFailed.
+func (s *Task) fail(failures ...Failure) Status {
+	for _, failure := range failures {
+		s.results.AddFailure(failure)
+	}
+	return StatusFailed
+}
+
+// runAttempt runs the command once. It returns the Status the
+// attempt ended with rather than recording it, because a failed
+// attempt that's going to be retried shouldn't look like a
+// failure to the Task's dependents.
+//
+// A command that can't be started fails like any other, with a
+// FailureStart, rather than making RunAll give up.
+func (s *Task) runAttempt(parent context.Context, updateHandler func(*Task)) Status {
+	var (
+		wg sync.WaitGroup
+		// readErr is the first error reading the command's
+		// output, if there is one. readMtx guards it.
+		readMtx sync.Mutex
+		readErr error
+	)
+	setReadErr := func(err error) {
+		readMtx.Lock()
+		defer readMtx.Unlock()
+		if readErr == nil {
+			readErr = err
+		}
+	}
+	ctx := parent
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(s.Timeout))
+		defer cancel()
+	}
+	commandLine := s.CommandLine()
+	cmd := exec.Command(commandLine[0], commandLine[1:]...)
 	cmd.Env = s.env()
+	cmd.Dir = s.WorkingDirectory
+	setProcessGroup(cmd)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf(`couldn't open standard out for command %q %v: %w`, s.Command, s.Args, err)
+		return s.fail(newFailure(FailureStart, `couldn't open standard out for command %q: %v`, commandLine, err))
 	}
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
-		return fmt.Errorf(`couldn't open standard error for command %q %v: %w`, s.Command, s.Args, err)
+		return s.fail(newFailure(FailureStart, `couldn't open standard error for command %q: %v`, commandLine, err))
 	}
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf(`couldn't start command %q %v: %w`, s.Command, s.Args, err)
+		return s.fail(newFailure(FailureStart, `couldn't start command %q: %v`, commandLine, err))
 	}
+	exited := supervise(ctx, cmd)
 	wg.Add(2)
 	go func() {
 		defer stdout.Close()
@@ -132,13 +346,12 @@ func (s *Task) Run(updateHandler func(*Task)) error {
 		for {
 			n, err := stdout.Read(buff)
 			if n > 0 {
-				s.results.AppendStdOut(string(buff))
+				s.results.AppendStdOut(string(buff[:n]))
 				updateHandler(s)
 			}
 			if err != nil {
 				if err != io.EOF {
-					fmt.Println(`Could not read std in from task`, s.Name, `read bytes`, n, err)
-					s.results.SetStatus(StatusFailed)
+					setReadErr(fmt.Errorf(`standard out: %w`, err))
 				}
 				return
 			}
@@ -152,25 +365,45 @@ func (s *Task) Run(updateHandler func(*Task)) error {
 		for {
 			n, err := stderr.Read(buff)
 			if n > 0 {
-				s.results.AppendStdErr(string(buff))
+				s.results.AppendStdErr(string(buff[:n]))
 				updateHandler(s)
 			}
 			if err != nil {
 				if err != io.EOF {
-					fmt.Println(err)
-					s.results.SetStatus(StatusFailed)
+					setReadErr(fmt.Errorf(`standard error: %w`, err))
 				}
 				return
 			}
 		}
 	}()
 	wg.Wait()
-	if err := cmd.Wait(); err != nil {
-		s.results.SetStatus(StatusFailed)
-		s.results.AppendStdErr(fmt.Sprintf(`command failed %q %v: %v`, s.Command, s.Args, err))
+	err = cmd.Wait()
+	if cmd.ProcessState != nil {
+		s.results.SetReturnCode(cmd.ProcessState.ExitCode())
 	}
-	s.results.SetReturnCode(cmd.ProcessState.ExitCode())
-	s.evaluateSuccess()
-	updateHandler(s)
-	return nil
+	if exited() {
+		if parent.Err() != nil {
+			s.results.AppendStdErr(fmt.Sprintf(`command cancelled: %q`, commandLine))
+			return StatusCancelled
+		}
+		s.results.AddFailure(newFailure(FailureTimedOut, `timed out after %v`, s.Timeout))
+		return StatusTimedOut
+	}
+	// Exiting with a code other than 0 isn't a problem in
+	// itself: the ExpectedReturnCode says whether it is.
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return s.fail(newFailure(FailureRead, `command failed %q: %v`, commandLine, err))
+	}
+	// The readers have finished, so readErr needs no locking.
+	if readErr != nil {
+		return s.fail(newFailure(FailureRead, `couldn't read the output of command %q: %v`, commandLine, readErr))
+	}
+	if failures := s.checkExpectations(); len(failures) > 0 {
+		return s.fail(failures...)
+	}
+	if failure := s.captureOutputs(); failure != nil {
+		return s.fail(*failure)
+	}
+	return StatusSucceeded
 }
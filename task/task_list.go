@@ -1,11 +1,10 @@
 package task
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"path/filepath"
 	"strings"
-
-	"github.com/Unquabain/fac/util"
 )
 
 // TaskList represents all the Tasks found in the task file (YAML)
@@ -15,24 +14,55 @@ type TaskList map[string]*Task
 
 // UnmarshalYAML decorates the Tasks found in the YAML task file
 // with some additional properties and initializes the Tasks'
-// internal structures.
+// internal structures. Top-level Settings are applied to the
+// Tasks as defaults, and the Vars are filled in.
 func (sl TaskList) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	temp := make(map[string]*Task)
+	return sl.parse(unmarshal, nil)
+}
+
+// parse does the work of UnmarshalYAML. The overrides take
+// precedence over the Vars in the task file. It returns an error
+// listing any variables the Tasks use that aren't defined.
+func (sl TaskList) parse(unmarshal func(interface{}) error, overrides map[string]string) error {
+	var temp struct {
+		Settings `yaml:",inline"`
+		Tasks    map[string]*Task `yaml:",inline"`
+	}
 	err := unmarshal(&temp)
 	if err != nil {
 		return err
 	}
+	vars := resolveVars(temp.Vars, overrides)
+	usedBy := make(map[string][]string)
 	count := 0
-	for key, task := range temp {
+	for key, task := range temp.Tasks {
+		temp.Settings.apply(task)
 		task.Name = key
 		task.Order = count
 		count++
 		task.results = NewResultsProxy()
+		for name := range task.interpolate(vars) {
+			usedBy[name] = append(usedBy[name], key)
+		}
 		sl[key] = task
 	}
+	if len(usedBy) > 0 {
+		return undefinedVarsError(usedBy)
+	}
 	return nil
 }
 
+// ResolveWorkingDirectories makes the Tasks' relative
+// WorkingDirectory paths relative to base, the directory the task
+// file is in, rather than to fac's working directory.
+func (sl TaskList) ResolveWorkingDirectories(base string) {
+	for _, task := range sl {
+		if task.WorkingDirectory != `` && !filepath.IsAbs(task.WorkingDirectory) {
+			task.WorkingDirectory = filepath.Join(base, task.WorkingDirectory)
+		}
+	}
+}
+
 func parseDependencyName(depencencyName string) (key string, positive bool) {
 	positive = true
 	key = strings.TrimSpace(depencencyName)
@@ -43,6 +73,34 @@ func parseDependencyName(depencencyName string) (key string, positive bool) {
 	return
 }
 
+// Subset returns a new TaskList with just the named Tasks and
+// everything they depend on, directly or not, negated or not.
+func (sl TaskList) Subset(names ...string) (TaskList, error) {
+	subset := make(TaskList)
+	pending := make([]string, len(names))
+	copy(pending, names)
+	for len(pending) > 0 {
+		name := pending[0]
+		pending = pending[1:]
+		if _, ok := subset[name]; ok {
+			continue
+		}
+		task, ok := sl[name]
+		if !ok {
+			return nil, fmt.Errorf(`task not found: %q`, name)
+		}
+		subset[name] = task
+		for _, dep := range task.Dependencies {
+			key, _ := parseDependencyName(dep)
+			if _, ok := sl[key]; !ok {
+				return nil, fmt.Errorf(`dependency not found for %q: %q`, task.Name, dep)
+			}
+			pending = append(pending, key)
+		}
+	}
+	return subset, nil
+}
+
 // IsRunnable examines a Task's dependency list and determines
 // if it has been satisfied.
 func (sl TaskList) IsRunnable(task *Task) (bool, error) {
@@ -58,16 +116,12 @@ func (sl TaskList) IsRunnable(task *Task) (bool, error) {
 		if !ok {
 			return false, fmt.Errorf(`dependency not found for %q: %q`, task.Name, dep)
 		}
-		successStatus, failedStatus := StatusSucceeded, StatusFailed
-		if !positive {
-			successStatus, failedStatus = failedStatus, successStatus
-		}
 		dsStatus := depTask.GetStatus()
-		if dsStatus == failedStatus || dsStatus == StatusDependenciesNotMet {
-			task.results.SetStatus(StatusDependenciesNotMet)
+		if !dsStatus.IsFinal() {
 			return false, nil
 		}
-		if dsStatus != successStatus {
+		if !dependencyMet(dsStatus, positive) {
+			task.results.SetStatus(StatusDependenciesNotMet)
 			return false, nil
 		}
 	}
@@ -75,7 +129,8 @@ func (sl TaskList) IsRunnable(task *Task) (bool, error) {
 }
 
 // ReadyToRun returns a list of all the Tasks that are currently
-// ready to run because their dependencies have been satisified.
+// ready to run because their dependencies have been satisified,
+// in the order they should start in (see Estimates.Sort).
 func (sl TaskList) ReadyToRun() ([]*Task, error) {
 	runnables := make([]*Task, 0, len(sl))
 	for _, task := range sl {
@@ -87,6 +142,7 @@ func (sl TaskList) ReadyToRun() ([]*Task, error) {
 			runnables = append(runnables, task)
 		}
 	}
+	sl.Estimate(nil).Sort(runnables)
 	return runnables, nil
 }
 
@@ -94,89 +150,96 @@ func (sl TaskList) ReadyToRun() ([]*Task, error) {
 // run have been run (successfully or not).
 func (sl TaskList) IsFinished() bool {
 	for _, task := range sl {
-		if task.GetStatus() == StatusNotRun {
+		if status := task.GetStatus(); status == StatusNotRun || status == StatusQueued {
 			return false
 		}
 	}
 	return true
 }
 
+// RunOptions tune how RunAll goes about running the Tasks.
+type RunOptions struct {
+	// Jobs is the most Tasks that may run at the same time.
+	// Zero means there's no limit.
+	Jobs int
+
+	// Resources are the sizes of the resource pools that
+	// Tasks share. Pools that aren't listed have a size
+	// of one.
+	Resources map[string]int
+
+	// StateDir is where to remember what Tasks with Sources
+	// or Generates last succeeded with, so that they can be
+	// skipped when nothing has changed. If it's blank, they
+	// always run.
+	StateDir string
+
+	// Force runs Tasks even if they're up to date, though
+	// what they run with is still remembered.
+	Force bool
+
+	// OutputDir is where the lines of output that Tasks drop
+	// from memory to stay under their OutputLimit are spilled,
+	// in files named after the Task, the attempt and the
+	// stream. If it's blank, they're lost.
+	OutputDir string
+
+	// History are the Records of an earlier run, like the last
+	// one's Journal. How long the Tasks took in it is used to
+	// start the ones on the critical path first.
+	History Records
+}
+
 // RunAll runs all the Tasks, resolving their dependencies to
 // run as many as it can in parallel. The function blocks until
 // all Tasks have been run, but the handler() callback will be
 // called several times for each task from different goroutines.
 //
 // First, all the tasks that have no dependencies are run in
-// parallel. After each task finishes, the TaskList checks to
-// see if any more tasks have had their dependencies satisfied
-// and launches those. The procedure runs until all tasks have
+// parallel. After each task finishes, the tasks that depend on
+// it are checked to see if they have had all their dependencies
+// satisfied, and those are launched. The procedure runs until all tasks have
 // either run or been marked unrunnable (because their
 // dependencies failed).
 //
+// Tasks that are ready to run are marked StatusQueued, and are
+// only launched once fewer than opts.Jobs Tasks are running and
+// there's one of each of their Resources free. When several are
+// waiting, they're launched by Priority, then by how long the
+// chain of Tasks after them took in opts.History.
+//
+// With opts.StateDir, Tasks whose Sources and Generates haven't
+// changed since they last succeeded are marked StatusUpToDate
+// rather than run, unless opts.Force is set.
+//
+// Before running anything, the TaskList is checked with Validate,
+// and RunAll returns an error if there are any problems.
+//
+// If ctx is cancelled, the running Tasks are stopped, the ones
+// that haven't started are marked StatusCancelled, and RunAll
+// returns ctx.Err() once the running Tasks have exited.
+//
 // If it ever finds that there are no currently running Tasks,
 // but no runnable Tasks, but Tasks that have not yet been run,
 // it returns an error. It will also return an error if at least
-// one Task returns an error, though it may accumulate more errors,
-// which are printed on STDERR.
-func (sl TaskList) RunAll(handler func(*Task)) error {
-	runningTasks := new(util.Counter)
-	errors := util.NewErrorList()
-	gate := make(chan struct{})
-
-	// Keep looping until all tasks report either finished,
-	// skipped, or failed.
-	for !sl.IsFinished() {
-		rtr, err := sl.ReadyToRun() // All dependencies met successfully
-		if err != nil {
-			return fmt.Errorf(`failed determine runnable tasks: %w`, err)
-		}
-		newTasks := len(rtr)
-		if runningTasks.Val() == 0 && newTasks == 0 && !sl.IsFinished() {
-			// No running tasks, no new tasks, but some tasks are still
-			// waiting to run. That means a dependency loop.
-			taskdump := new(strings.Builder)
-			for name, task := range sl {
-				fmt.Fprintf(taskdump, "%s: %s\n", name, task.GetStatus())
-				for _, dep := range task.Dependencies {
-					fmt.Fprintf(taskdump, "\t- %s\n", dep)
-				}
-			}
-			return fmt.Errorf("deadlock detected: not finished, but not ready to run\n%s", taskdump.String())
-		}
+// one Task returns an error. In that case, it starts no more
+// Tasks, and waits for the running ones to finish, printing any
+// more errors they return on STDERR.
+func (sl TaskList) RunAll(ctx context.Context, opts RunOptions, handler func(*Task)) error {
+	return sl.runAll(ctx, opts, handler, runTask)
+}
 
-		// Keep track of how many tasks are in-flight.
-		runningTasks.Add(newTasks)
-
-		// This loop may be empty if there are still
-		// tasks running.
-		for _, task := range rtr {
-			go func(s *Task) {
-				defer func() {
-					runningTasks.Dec()
-					// Gate is unbuffered. This will block until
-					// the loop comes round again.
-					gate <- struct{}{}
-				}()
-				errInner := s.Run(handler)
-				if errInner != nil {
-					errors.Appendf(`error running task %q: %w`, s.Name, errInner)
-					return
-				}
-			}(task)
-		}
-		// Wait until one task finishes, then loop around
-		// to re-evaluate if any tasks have had their
-		// dependencies successfully run.
-		// Because the above loop may not run, <-gate should
-		// be called as many times as gate <- struct{}{} is
-		// called.
-		<-gate
-		if errors.Len() > 0 {
-			for _, err = range errors.Errors() {
-				log.Println(err)
-			}
-			return fmt.Errorf(`received one or more errors running tasks, the last of which is %w`, err)
+func (sl TaskList) runAll(ctx context.Context, opts RunOptions, handler func(*Task), run runner) error {
+	if problems := sl.Validate(); len(problems) > 0 {
+		return fmt.Errorf(`invalid task list: %w`, problems[0])
+	}
+	sc := newScheduler(sl, opts, handler, run)
+	if opts.StateDir != `` {
+		st, err := loadState(opts.StateDir)
+		if err != nil {
+			return err
 		}
+		sc.state = st
 	}
-	return nil
+	return sc.runAll(ctx)
 }
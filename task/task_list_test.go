@@ -1,8 +1,12 @@
 package task
 
 import (
+	"context"
 	"fmt"
+	"math/rand"
+	"sync"
 	"testing"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 )
@@ -97,6 +101,55 @@ func TestUnmarshalYAML(t *testing.T) {
 
 }
 
+func TestUnmarshalYAMLSettings(t *testing.T) {
+	list, err := getTaskListFromYaml(`---
+timeout: 1m
+Quick:
+  command: "true"
+  timeout: 5s
+Slow:
+  command: "true"
+`)
+	if err != nil {
+		t.Fatalf(`could not test settings: %v`, err)
+	}
+	if actual := len(list); actual != 2 {
+		t.Fatalf(`expected settings not to be parsed as tasks; found %d tasks`, actual)
+	}
+	if actual := list[`Quick`].Timeout; actual != Duration(5*time.Second) {
+		t.Fatalf(`expected "Quick" to keep its own timeout of 5s; was %v`, actual)
+	}
+	if actual := list[`Slow`].Timeout; actual != Duration(time.Minute) {
+		t.Fatalf(`expected "Slow" to have the default timeout of 1m; was %v`, actual)
+	}
+}
+
+func TestResolveWorkingDirectories(t *testing.T) {
+	list, err := getTaskListFromYaml(`---
+workingDirectory: web
+Build:
+  command: make
+Deploy:
+  command: ./deploy
+  workingDirectory: /srv/app
+Lint:
+  command: lint
+  workingDirectory: ../lib
+`)
+	if err != nil {
+		t.Fatalf(`could not test working directories: %v`, err)
+	}
+	list.ResolveWorkingDirectories(`/home/me/project`)
+	expect := func(name, dir string) {
+		if actual := list[name].WorkingDirectory; actual != dir {
+			t.Fatalf(`expected %q to run in %q; was %q`, name, dir, actual)
+		}
+	}
+	expect(`Build`, `/home/me/project/web`)
+	expect(`Deploy`, `/srv/app`)
+	expect(`Lint`, `/home/me/lib`)
+}
+
 func TestIsRunnable(t *testing.T) {
 	list, err := getTaskListFromYaml(exampleYAML)
 	if err != nil {
@@ -201,6 +254,23 @@ func TestIsRunnable(t *testing.T) {
 		}
 		t.Fatalf(`expected "Only On Fail" to be runnable; wasn't`)
 	}
+
+	// Timing out counts as failing.
+	clearLogs.results.SetStatus(StatusTimedOut)
+	onlyOnFail.results.SetStatus(StatusNotRun)
+	if actual, err := list.IsRunnable(onlyOnFail); err != nil || !actual {
+		if err != nil {
+			t.Fatalf(`couldn't tell if "Only On Fail" was runnable: %v`, err)
+		}
+		t.Fatalf(`expected "Only On Fail" to be runnable; wasn't`)
+	}
+	updateBundler.results.SetStatus(StatusNotRun)
+	if actual, err := list.IsRunnable(updateBundler); err != nil || actual {
+		if err != nil {
+			t.Fatalf(`couldn't tell if "Update Bundler" was runnable: %v`, err)
+		}
+		t.Fatalf(`expected "Update Bundler" not to be runnable; was`)
+	}
 }
 
 func TestReadyToRun(t *testing.T) {
@@ -252,3 +322,280 @@ func TestIsFinished(t *testing.T) {
 		t.Fatalf(`expected list to be finished, but was not`)
 	}
 }
+
+// runAllCountingConcurrency runs the list and reports the most
+// Tasks that were running at the same time, and which pairs of
+// Tasks ran at the same time.
+func runAllCountingConcurrency(t *testing.T, list TaskList, opts RunOptions) (int, map[string]bool) {
+	var mtx sync.Mutex
+	running := make(map[string]bool)
+	together := make(map[string]bool)
+	maxRunning := 0
+	err := list.RunAll(context.Background(), opts, func(s *Task) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		running[s.Name] = s.GetStatus() == StatusRunning
+		count := 0
+		for name, r := range running {
+			if r {
+				count++
+				if name != s.Name && running[s.Name] {
+					together[name+` & `+s.Name] = true
+					together[s.Name+` & `+name] = true
+				}
+			}
+		}
+		if count > maxRunning {
+			maxRunning = count
+		}
+	})
+	if err != nil {
+		t.Fatalf(`could not run tasks: %v`, err)
+	}
+	for name, task := range list {
+		if actual := task.GetStatus(); actual != StatusSucceeded {
+			t.Fatalf(`expected %q to succeed; was %v`, name, actual)
+		}
+	}
+	return maxRunning, together
+}
+
+var sleepyYAML = `---
+One:
+  command: sleep
+  args: ["0.2"]
+  resources: [db]
+Two:
+  command: sleep
+  args: ["0.2"]
+  resources: [db, port]
+Three:
+  command: sleep
+  args: ["0.2"]
+  resources: [port]
+`
+
+func TestRunAllWithJobs(t *testing.T) {
+	list, err := getTaskListFromYaml(sleepyYAML)
+	if err != nil {
+		t.Fatalf(`could not test RunAll: %v`, err)
+	}
+	opts := RunOptions{
+		Jobs:      2,
+		Resources: map[string]int{`db`: 3, `port`: 3},
+	}
+	if actual, _ := runAllCountingConcurrency(t, list, opts); actual != 2 {
+		t.Fatalf(`expected at most 2 tasks to run at once; %d did`, actual)
+	}
+}
+
+func TestRunAllWithResources(t *testing.T) {
+	list, err := getTaskListFromYaml(sleepyYAML)
+	if err != nil {
+		t.Fatalf(`could not test RunAll: %v`, err)
+	}
+	// "port" isn't given a size, so it's a mutex.
+	_, together := runAllCountingConcurrency(t, list, RunOptions{Resources: map[string]int{`db`: 1}})
+	if together[`One & Two`] {
+		t.Fatalf(`expected "One" and "Two" never to run together; they did`)
+	}
+	if together[`Two & Three`] {
+		t.Fatalf(`expected "Two" and "Three" never to run together; they did`)
+	}
+}
+
+func TestRunAllWithCancellation(t *testing.T) {
+	list, err := getTaskListFromYaml(`---
+Slow:
+  command: sleep
+  args: ["5"]
+After Slow:
+  command: "true"
+  dependencies: [Slow]
+`)
+	if err != nil {
+		t.Fatalf(`could not test RunAll: %v`, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	start := time.Now()
+	time.AfterFunc(100*time.Millisecond, cancel)
+	err = list.RunAll(ctx, RunOptions{}, func(s *Task) {})
+	if err != context.Canceled {
+		t.Fatalf(`expected RunAll to report it was cancelled; got %v`, err)
+	}
+	if actual := time.Since(start); actual > 2*time.Second {
+		t.Fatalf(`expected RunAll to stop soon after being cancelled; took %v`, actual)
+	}
+	for _, name := range []string{`Slow`, `After Slow`} {
+		if actual := list[name].GetStatus(); actual != StatusCancelled {
+			t.Fatalf(`expected %q to be cancelled; was %v`, name, actual)
+		}
+	}
+}
+
+// fakeRunner returns a runner that doesn't start any processes,
+// but ends each Task with the Status given for it, or succeeds.
+func fakeRunner(outcomes map[string]Status) runner {
+	return func(ctx context.Context, s *Task, handler func(*Task)) error {
+		s.results.SetStatus(StatusRunning)
+		handler(s)
+		status, ok := outcomes[s.Name]
+		if !ok {
+			status = StatusSucceeded
+		}
+		s.results.SetStatus(status)
+		handler(s)
+		return nil
+	}
+}
+
+func TestRunAllResolvesDependents(t *testing.T) {
+	list, err := getTaskListFromYaml(`---
+Load DB Dump:
+  command: pgrestore
+Migrate DB:
+  command: rake
+  dependencies: ["! Load DB Dump"]
+Seed DB:
+  command: rake
+  dependencies: [Load DB Dump]
+Run Specs:
+  command: rspec
+  dependencies: [Seed DB, Migrate DB]
+Lint:
+  command: rubocop
+  dependencies: [Migrate DB]
+`)
+	if err != nil {
+		t.Fatalf(`could not test RunAll: %v`, err)
+	}
+	run := fakeRunner(map[string]Status{`Load DB Dump`: StatusTimedOut})
+	if err := list.runAll(context.Background(), RunOptions{}, func(*Task) {}, run); err != nil {
+		t.Fatalf(`could not run tasks: %v`, err)
+	}
+	expect := func(name string, status Status) {
+		if actual := list[name].GetStatus(); actual != status {
+			t.Fatalf(`expected %q to be %v; was %v`, name, status, actual)
+		}
+	}
+	expect(`Load DB Dump`, StatusTimedOut)
+	expect(`Migrate DB`, StatusSucceeded)
+	expect(`Seed DB`, StatusDependenciesNotMet)
+	expect(`Run Specs`, StatusDependenciesNotMet)
+	expect(`Lint`, StatusSucceeded)
+}
+
+// generateTaskList makes a TaskList of n Tasks, each depending on
+// up to three of the 50 Tasks before it, so the graph is both wide
+// and deep.
+func generateTaskList(n int) TaskList {
+	rng := rand.New(rand.NewSource(1))
+	list := make(TaskList, n)
+	for i := 0; i < n; i++ {
+		task := &Task{
+			Name:    fmt.Sprintf(`Task %d`, i),
+			Command: `true`,
+			Order:   i,
+			results: NewResultsProxy(),
+		}
+		for j := 0; j < 3 && i > 0; j++ {
+			first := i - 50
+			if first < 0 {
+				first = 0
+			}
+			dep := fmt.Sprintf(`Task %d`, first+rng.Intn(i-first))
+			task.Dependencies = append(task.Dependencies, dep)
+		}
+		list[task.Name] = task
+	}
+	return list
+}
+
+// pollingRunAll schedules the way RunAll used to: rescanning the
+// whole TaskList with ReadyToRun and IsFinished each time a Task
+// finishes.
+func pollingRunAll(sl TaskList, run runner) error {
+	queue := make([]*Task, 0, len(sl))
+	for !sl.IsFinished() {
+		rtr, err := sl.ReadyToRun()
+		if err != nil {
+			return err
+		}
+		for _, task := range rtr {
+			task.results.SetStatus(StatusQueued)
+		}
+		queue = append(queue, rtr...)
+		if len(queue) == 0 {
+			return fmt.Errorf(`deadlock`)
+		}
+		if err := run(context.Background(), queue[0], func(*Task) {}); err != nil {
+			return err
+		}
+		queue = queue[1:]
+	}
+	return nil
+}
+
+func BenchmarkRunAll(b *testing.B) {
+	run := fakeRunner(nil)
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		list := generateTaskList(2000)
+		b.StartTimer()
+		if err := list.runAll(context.Background(), RunOptions{}, func(*Task) {}, run); err != nil {
+			b.Fatalf(`could not run tasks: %v`, err)
+		}
+	}
+}
+
+func BenchmarkPollingRunAll(b *testing.B) {
+	run := fakeRunner(nil)
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		list := generateTaskList(2000)
+		b.StartTimer()
+		if err := pollingRunAll(list, run); err != nil {
+			b.Fatalf(`could not run tasks: %v`, err)
+		}
+	}
+}
+
+func TestSubset(t *testing.T) {
+	list, err := getTaskListFromYaml(`---
+Update Repo:
+  command: git
+Update JS Deps:
+  command: npm
+  dependencies: [Update Repo]
+Update Gems:
+  command: bundle
+  dependencies: [Update Repo]
+Load DB Dump:
+  command: pgrestore
+  dependencies: [Update Gems]
+Migrate DB:
+  command: rake
+  dependencies: ["! Load DB Dump"]
+`)
+	if err != nil {
+		t.Fatalf(`could not test Subset: %v`, err)
+	}
+	subset, err := list.Subset(`Migrate DB`)
+	if err != nil {
+		t.Fatalf(`could not take subset: %v`, err)
+	}
+	for _, name := range []string{`Migrate DB`, `Load DB Dump`, `Update Gems`, `Update Repo`} {
+		if actual, ok := subset[name]; !ok || actual != list[name] {
+			t.Fatalf(`expected subset to include %q; didn't`, name)
+		}
+	}
+	if _, ok := subset[`Update JS Deps`]; ok {
+		t.Fatalf(`expected subset not to include "Update JS Deps"; did`)
+	}
+	if actual := len(subset); actual != 4 {
+		t.Fatalf(`expected 4 tasks in the subset; found %d`, actual)
+	}
+	if _, err := list.Subset(`Deploy`); err == nil {
+		t.Fatalf(`expected an error taking a subset with an unknown task; received none`)
+	}
+}
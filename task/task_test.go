@@ -1,7 +1,13 @@
 package task
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func newSuccessfulTask() *Task {
@@ -27,7 +33,7 @@ func newFailValidationTask() *Task {
 func TestRunWithSuccess(t *testing.T) {
 	task := newSuccessfulTask()
 	updatesCount := 0
-	err := task.Run(func(s *Task) { updatesCount++ })
+	err := task.Run(context.Background(), func(s *Task) { updatesCount++ })
 	if err != nil {
 		t.Fatalf(`problem running command: %v`, err)
 	}
@@ -37,12 +43,142 @@ func TestRunWithSuccess(t *testing.T) {
 	if actual := updatesCount; actual < 3 {
 		t.Fatalf(`expected at least 3 updates: received %d`, actual)
 	}
+	if started, finished := task.GetStarted(), task.GetFinished(); started.IsZero() || finished.Before(started) {
+		t.Fatalf(`expected the task to record when it started and finished; was %v to %v`, started, finished)
+	}
+}
+
+func TestRunInWorkingDirectory(t *testing.T) {
+	task := newSuccessfulTask()
+	task.Args = []string{`success_data.txt`}
+	task.WorkingDirectory = `test_data`
+	err := task.Run(context.Background(), func(s *Task) {})
+	if err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusSucceeded {
+		t.Fatalf(`task should have succeeded in its working directory; didn't: %v`, actual)
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	task := newSuccessfulTask()
+	task.Command = ``
+	task.Args = nil
+	task.Script = "cat ./test_data/success_data.txt | tr a-z A-Z\nfalse\necho 'not reached'"
+	err := task.Run(context.Background(), func(s *Task) {})
+	if err != nil {
+		t.Fatalf(`problem running script: %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusFailed {
+		t.Fatalf(`expected the default shell to stop at the first failing line; status was %v`, actual)
+	}
+	if actual := task.GetStdOut(); !strings.Contains(actual, `SUCCESSFUL VALUE`) || strings.Contains(actual, `not reached`) {
+		t.Fatalf(`expected the script to run up to the failing line; output was %q`, actual)
+	}
+}
+
+func TestRunWithOutputLimit(t *testing.T) {
+	task := newSuccessfulTask()
+	task.Command = `seq`
+	task.Args = []string{`1000`}
+	task.ExpectedStdOutRegex = `1000`
+	task.OutputLimit = 100
+	task.outputDir = t.TempDir()
+	err := task.Run(context.Background(), func(s *Task) {})
+	if err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusSucceeded {
+		t.Fatalf(`task should have succeeded; didn't: %v`, actual)
+	}
+	stdOut := task.GetStdOut()
+	if len(stdOut) > 100 || !strings.HasSuffix(stdOut, "999\n1000\n") {
+		t.Fatalf(`expected only the last 100 bytes of output to be kept; got %q`, stdOut)
+	}
+	buff, err := ioutil.ReadFile(filepath.Join(task.outputDir, `Test_Task.1.stdout`))
+	if err != nil {
+		t.Fatalf(`expected the rest of the output to be spilled: %v`, err)
+	}
+	if actual := string(buff) + stdOut; !strings.HasPrefix(actual, "1\n2\n3\n") || strings.Count(actual, "\n") != 1000 {
+		t.Fatalf(`expected the spilled output and what's in memory to add up to all of it`)
+	}
+}
+
+func TestCommandLineForScript(t *testing.T) {
+	task := &Task{Script: `make | tee build.log`, Shell: `bash -eo pipefail`}
+	expected := []string{`bash`, `-eo`, `pipefail`, `-c`, `make | tee build.log`}
+	if actual := task.CommandLine(); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Fatalf(`expected command line %q; was %q`, expected, actual)
+	}
+}
+
+func TestRunWithEnvironment(t *testing.T) {
+	t.Setenv(`FAC_INHERITED`, `inherited`)
+	task := newSuccessfulTask()
+	task.Command = `sh`
+	task.Args = []string{`-c`, `echo "$FAC_INHERITED $FAC_OWN"`}
+	task.Environment[`FAC_OWN`] = `own`
+	task.ExpectedStdOutRegex = ``
+	err := task.Run(context.Background(), func(s *Task) {})
+	if err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStdOut(); actual != "inherited own\n" {
+		t.Fatalf(`expected the task to see both fac's environment and its own; saw %q`, actual)
+	}
+}
+
+func TestRunFailures(t *testing.T) {
+	task := newFailValidationTask()
+	task.ExpectedReturnCode = 2
+	if err := task.Run(context.Background(), func(s *Task) {}); err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	failures := task.GetFailures()
+	expected := []Failure{
+		{Reason: FailureReturnCode, ExpectedReturnCode: 2, ReturnCode: 0, Message: `return code was 0, expected 2`},
+		{Reason: FailureStdOut, Pattern: `Successful Value`, Message: `STDOUT didn't match "Successful Value"`},
+	}
+	if fmt.Sprintf(`%#v`, failures) != fmt.Sprintf(`%#v`, expected) {
+		t.Fatalf(`expected %#v; got %#v`, expected, failures)
+	}
+}
+
+func TestExpectedNonZeroReturnCode(t *testing.T) {
+	task := newSuccessfulTask()
+	task.Command = `sh`
+	task.Args = []string{`-c`, `exit 3`}
+	task.ExpectedStdOutRegex = ``
+	task.ExpectedReturnCode = 3
+	if err := task.Run(context.Background(), func(s *Task) {}); err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusSucceeded {
+		t.Fatalf(`expected the task to succeed; it %v: %v`, actual, task.GetFailures())
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	task := newSuccessfulTask()
+	task.Command = `fac-no-such-command`
+	task.Args = nil
+	if err := task.Run(context.Background(), func(s *Task) {}); err != nil {
+		t.Fatalf(`expected a start failure to fail the task, not Run; got %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusFailed {
+		t.Fatalf(`expected the task to fail; it %v`, actual)
+	}
+	failures := task.GetFailures()
+	if len(failures) != 1 || failures[0].Reason != FailureStart {
+		t.Fatalf(`expected a start failure; got %#v`, failures)
+	}
 }
 
 func TestRunWithFailure(t *testing.T) {
 	task := newFailValidationTask()
 	updatesCount := 0
-	err := task.Run(func(s *Task) { updatesCount++ })
+	err := task.Run(context.Background(), func(s *Task) { updatesCount++ })
 	if err != nil {
 		t.Fatalf(`problem running command: %v`, err)
 	}
@@ -53,3 +189,124 @@ func TestRunWithFailure(t *testing.T) {
 		t.Fatalf(`expected at least 3 updates: received %d`, actual)
 	}
 }
+
+func TestRunWithTimeout(t *testing.T) {
+	task := newSuccessfulTask()
+	task.Command = `sleep`
+	task.Args = []string{`5`}
+	task.Timeout = Duration(100 * time.Millisecond)
+	start := time.Now()
+	err := task.Run(context.Background(), func(s *Task) {})
+	if err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusTimedOut {
+		t.Fatalf(`task should have timed out; didn't: %v`, actual)
+	}
+	if actual := time.Since(start); actual > 2*time.Second {
+		t.Fatalf(`task should have been stopped after its timeout; ran for %v`, actual)
+	}
+}
+
+func TestRunWithTimeoutIgnoringSigterm(t *testing.T) {
+	defer func(grace time.Duration) { killGracePeriod = grace }(killGracePeriod)
+	killGracePeriod = 200 * time.Millisecond
+	task := newSuccessfulTask()
+	task.Command = `sh`
+	task.Args = []string{`-c`, `trap "" TERM; while true; do sleep 0.1; done`}
+	task.Timeout = Duration(100 * time.Millisecond)
+	start := time.Now()
+	err := task.Run(context.Background(), func(s *Task) {})
+	if err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusTimedOut {
+		t.Fatalf(`task should have timed out; didn't: %v`, actual)
+	}
+	if actual := time.Since(start); actual > 2*time.Second {
+		t.Fatalf(`task should have been killed after the grace period; ran for %v`, actual)
+	}
+}
+
+func newFlakyTask(t *testing.T, succeedOn int) *Task {
+	task := newSuccessfulTask()
+	counter := filepath.Join(t.TempDir(), `attempts`)
+	task.Command = `sh`
+	task.Args = []string{
+		`-c`,
+		fmt.Sprintf(`n=$(cat %[1]q 2>/dev/null || echo 0); n=$((n+1)); echo $n > %[1]q; echo "attempt $n"; [ $n -ge %[2]d ]`, counter, succeedOn),
+	}
+	task.ExpectedStdOutRegex = ``
+	task.Retries = 2
+	task.RetryDelay = Duration(10 * time.Millisecond)
+	task.RetryBackoff = 2
+	return task
+}
+
+func TestRunWithRetries(t *testing.T) {
+	task := newFlakyTask(t, 3)
+	err := task.Run(context.Background(), func(s *Task) {
+		if status := s.GetStatus(); status != StatusRunning && status != StatusSucceeded {
+			t.Errorf(`expected only running and succeeded statuses; received %v`, status)
+		}
+	})
+	if err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusSucceeded {
+		t.Fatalf(`task should have succeeded on its last attempt; didn't: %v`, actual)
+	}
+	if actual := task.GetAttempt(); actual != 3 {
+		t.Fatalf(`expected task to be on attempt 3; was on %d`, actual)
+	}
+	history := task.GetHistory()
+	if actual := len(history); actual != 2 {
+		t.Fatalf(`expected 2 failed attempts in the history; found %d`, actual)
+	}
+	if actual := history[0].StdOut; actual != "attempt 1\n" {
+		t.Fatalf(`expected first attempt's output to be "attempt 1\n"; was %q`, actual)
+	}
+	if actual := history[1].ReturnCode; actual != 1 {
+		t.Fatalf(`expected second attempt's return code to be 1; was %d`, actual)
+	}
+	if actual := task.GetStdOut(); actual != "attempt 3\n" {
+		t.Fatalf(`expected final attempt's output to be "attempt 3\n"; was %q`, actual)
+	}
+}
+
+func TestRunWithRetriesExhausted(t *testing.T) {
+	task := newFlakyTask(t, 4)
+	err := task.Run(context.Background(), func(s *Task) {})
+	if err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusFailed {
+		t.Fatalf(`task should have failed after its last attempt; didn't: %v`, actual)
+	}
+	if actual := task.GetAttempt(); actual != 3 {
+		t.Fatalf(`expected task to stop at attempt 3; was on %d`, actual)
+	}
+}
+
+func TestRunWithCancellation(t *testing.T) {
+	task := newSuccessfulTask()
+	task.Command = `sleep`
+	task.Args = []string{`5`}
+	task.Retries = 2
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := task.Run(ctx, func(s *Task) {})
+	if err != nil {
+		t.Fatalf(`problem running command: %v`, err)
+	}
+	if actual := task.GetStatus(); actual != StatusCancelled {
+		t.Fatalf(`task should have been cancelled; wasn't: %v`, actual)
+	}
+	if actual := task.GetAttempt(); actual != 1 {
+		t.Fatalf(`expected a cancelled task not to be retried; was on attempt %d`, actual)
+	}
+	if actual := time.Since(start); actual > 2*time.Second {
+		t.Fatalf(`task should have been stopped when cancelled; ran for %v`, actual)
+	}
+}
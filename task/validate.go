@@ -0,0 +1,292 @@
+package task
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Unquabain/fac/util"
+)
+
+// Problem is something wrong with a TaskList that would keep it
+// from running properly, found before running it.
+type Problem struct {
+	// Task is the name of the Task with the problem.
+	Task string
+
+	// Field is the YAML key of the Task's field with the
+	// problem, if the problem is with a particular field.
+	Field string
+
+	// Value is the text of the field's value, or the entry
+	// in it, with the problem, if there is one.
+	Value string
+
+	// Message describes the problem.
+	Message string
+
+	// Line is the line of the task file the problem was found
+	// on, if it's known. Set by Problems.Locate.
+	Line int
+}
+
+// Error satisfies the error interface.
+func (p Problem) Error() string {
+	return fmt.Sprintf(`%q: %s`, p.Task, p.Message)
+}
+
+// Problems is a list of Problems, in the order they were found.
+type Problems []Problem
+
+// Validate analyzes the whole TaskList for problems: unknown
+// dependencies, Tasks that depend on themselves, dependency
+// cycles, Tasks with both a run script and a command or args,
+// invalid expectedStdOutRegex, expectedStdErrRegex or outputs
+// patterns, invalid sources or generates globs, invalid templates
+// for outputs in the command, args and environment, templates
+// that refer to Tasks that aren't dependencies, and working
+// directories that don't exist. It reports all of them, in order
+// of Task name.
+func (sl TaskList) Validate() Problems {
+	problems := make(Problems, 0)
+	names := sl.names()
+	for _, name := range names {
+		task := sl[name]
+		for _, dep := range task.Dependencies {
+			key, _ := parseDependencyName(dep)
+			if key == name {
+				problems = append(problems, Problem{
+					Task:    name,
+					Field:   `dependencies`,
+					Value:   dep,
+					Message: `task depends on itself`,
+				})
+			} else if _, ok := sl[key]; !ok {
+				problems = append(problems, Problem{
+					Task:    name,
+					Field:   `dependencies`,
+					Value:   dep,
+					Message: fmt.Sprintf(`dependency not found: %q`, key),
+				})
+			}
+		}
+		if task.Script != `` && (task.Command != `` || len(task.Args) > 0) {
+			problems = append(problems, Problem{
+				Task:    name,
+				Field:   `run`,
+				Message: `run can't be given along with command or args`,
+			})
+		}
+		patterns := []struct{ field, pattern string }{
+			{`expectedStdOutRegex`, task.ExpectedStdOutRegex},
+			{`expectedStdErrRegex`, task.ExpectedStdErrRegex},
+			{`outputs`, task.Outputs.Pattern},
+		}
+		for _, p := range patterns {
+			if _, err := regexp.Compile(p.pattern); err != nil {
+				problems = append(problems, Problem{
+					Task:    name,
+					Field:   p.field,
+					Message: fmt.Sprintf(`invalid pattern: %v`, err),
+				})
+			}
+		}
+		templates := []struct{ field, text string }{{`command`, task.Command}}
+		for _, arg := range task.Args {
+			templates = append(templates, struct{ field, text string }{`args`, arg})
+		}
+		keys := make([]string, 0, len(task.Environment))
+		for key := range task.Environment {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			templates = append(templates, struct{ field, text string }{`environment`, task.Environment[key]})
+		}
+		dependencies := make(map[string]bool, len(task.Dependencies))
+		for _, dep := range task.Dependencies {
+			key, _ := parseDependencyName(dep)
+			dependencies[key] = true
+		}
+		for _, t := range templates {
+			if !isTemplate(t.text) {
+				continue
+			}
+			tmpl, err := template.New(``).Parse(t.text)
+			if err != nil {
+				problems = append(problems, Problem{
+					Task:    name,
+					Field:   t.field,
+					Value:   t.text,
+					Message: fmt.Sprintf(`invalid template: %v`, err),
+				})
+				continue
+			}
+			// Only the outputs of dependencies are filled in.
+			reported := make(map[string]bool)
+			for _, ref := range referencedTasks(tmpl.Tree) {
+				if !dependencies[ref] && !reported[ref] {
+					reported[ref] = true
+					problems = append(problems, Problem{
+						Task:    name,
+						Field:   t.field,
+						Value:   t.text,
+						Message: fmt.Sprintf(`template refers to a task that isn't a dependency: %q`, ref),
+					})
+				}
+			}
+		}
+		globs := []struct {
+			field    string
+			patterns []string
+		}{
+			{`sources`, task.Sources},
+			{`generates`, task.Generates},
+		}
+		for _, g := range globs {
+			for _, pattern := range g.patterns {
+				if err := util.ValidGlob(pattern); err != nil {
+					problems = append(problems, Problem{
+						Task:    name,
+						Field:   g.field,
+						Value:   pattern,
+						Message: fmt.Sprintf(`invalid glob: %v`, err),
+					})
+				}
+			}
+		}
+		if dir := task.WorkingDirectory; dir != `` {
+			if info, err := os.Stat(dir); err != nil {
+				problems = append(problems, Problem{
+					Task:    name,
+					Field:   `workingDirectory`,
+					Message: fmt.Sprintf(`working directory not found: %q`, dir),
+				})
+			} else if !info.IsDir() {
+				problems = append(problems, Problem{
+					Task:    name,
+					Field:   `workingDirectory`,
+					Message: fmt.Sprintf(`working directory is not a directory: %q`, dir),
+				})
+			}
+		}
+	}
+	return append(problems, sl.findCycles(names)...)
+}
+
+func (sl TaskList) names() []string {
+	names := make([]string, 0, len(sl))
+	for name := range sl {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// findCycles does a depth-first search through the dependencies
+// and reports every cycle it finds, with the whole path around it.
+// Self-dependencies and unknown dependencies are left to Validate.
+func (sl TaskList) findCycles(names []string) Problems {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	problems := make(Problems, 0)
+	state := make(map[string]int, len(sl))
+	path := make([]string, 0, len(sl))
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range sl[name].Dependencies {
+			key, _ := parseDependencyName(dep)
+			if _, ok := sl[key]; !ok || key == name {
+				continue
+			}
+			switch state[key] {
+			case unvisited:
+				visit(key)
+			case visiting:
+				start := 0
+				for path[start] != key {
+					start++
+				}
+				cycle := append(append([]string{}, path[start:]...), key)
+				problems = append(problems, Problem{
+					Task:    name,
+					Field:   `dependencies`,
+					Value:   dep,
+					Message: fmt.Sprintf(`dependency cycle: %s`, strings.Join(cycle, ` -> `)),
+				})
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+	}
+	for _, name := range names {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+	return problems
+}
+
+// Locate fills in the Line of each Problem by finding its Task,
+// field and value in source, the text of the task file.
+func (ps Problems) Locate(source []byte) {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(source))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	for i := range ps {
+		ps[i].Line = locate(lines, &ps[i])
+	}
+}
+
+// locate finds the line number of the Problem in the lines of a
+// task file. It looks for the Task's top-level key, then the
+// field within the Task, then the value within the field, and
+// settles for the closest it found.
+func locate(lines []string, p *Problem) int {
+	key := regexp.MustCompile(`^(["']?)` + regexp.QuoteMeta(p.Task) + `(["']?)\s*:`)
+	start := -1
+	for i, line := range lines {
+		if m := key.FindStringSubmatch(line); m != nil && m[1] == m[2] {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return 0
+	}
+	end := start + 1
+	for end < len(lines) && (lines[end] == `` || lines[end][0] == ' ' || lines[end][0] == '\t' || lines[end][0] == '#') {
+		end++
+	}
+	if p.Field == `` {
+		return start + 1
+	}
+	field := regexp.MustCompile(`^\s+` + regexp.QuoteMeta(p.Field) + `\s*:`)
+	for i := start + 1; i < end; i++ {
+		if !field.MatchString(lines[i]) {
+			continue
+		}
+		if p.Value == `` {
+			return i + 1
+		}
+		for j := i; j < end; j++ {
+			if strings.Contains(lines[j], p.Value) {
+				return j + 1
+			}
+		}
+		return i + 1
+	}
+	return start + 1
+}
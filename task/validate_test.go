@@ -0,0 +1,76 @@
+package task
+
+import "testing"
+
+var invalidYAML = `---
+Build:
+  command: make
+  dependencies:
+    - Configure
+Configure:
+  command: ./configure
+  dependencies:
+    - "! Package"
+Package:
+  command: tar
+  dependencies:
+    - Build
+Lint:
+  command: lint
+  dependencies: [Lint]
+  run: lint --fix
+Test:
+  command: make
+  dependencies:
+    - Biuld
+  expectedStdOutRegex: "(unclosed"
+  workingDirectory: test_data/missing
+Deploy:
+  command: "{{ .Tasks.Package.outputs.tool }}"
+  dependencies: ["Package"]
+  args: ["{{ .Tasks.Biuld.outputs.version }}", "{{ .Tasks.Package.outputs.file }}"]
+  environment:
+    NAME: '{{ index .Tasks "Test" "outputs" "name" }}{{ .Tasks.Test.outputs.name }}'
+`
+
+func TestValidate(t *testing.T) {
+	list, err := getTaskListFromYaml(invalidYAML)
+	if err != nil {
+		t.Fatalf(`could not test Validate: %v`, err)
+	}
+	problems := list.Validate()
+	problems.Locate([]byte(invalidYAML))
+	expected := []struct {
+		task    string
+		line    int
+		message string
+	}{
+		{`Deploy`, 27, `template refers to a task that isn't a dependency: "Biuld"`},
+		{`Deploy`, 29, `template refers to a task that isn't a dependency: "Test"`},
+		{`Lint`, 16, `task depends on itself`},
+		{`Lint`, 17, `run can't be given along with command or args`},
+		{`Test`, 21, `dependency not found: "Biuld"`},
+		{`Test`, 22, "invalid pattern: error parsing regexp: missing closing ): `(unclosed`"},
+		{`Test`, 23, `working directory not found: "test_data/missing"`},
+		{`Package`, 13, `dependency cycle: Build -> Configure -> Package -> Build`},
+	}
+	if actual := len(problems); actual != len(expected) {
+		t.Fatalf(`expected %d problems; found %d: %v`, len(expected), actual, problems)
+	}
+	for i, e := range expected {
+		p := problems[i]
+		if p.Task != e.task || p.Line != e.line || p.Message != e.message {
+			t.Fatalf(`expected problem %d to be %q on line %d: %q; was %q on line %d: %q`, i, e.task, e.line, e.message, p.Task, p.Line, p.Message)
+		}
+	}
+}
+
+func TestValidateWithoutProblems(t *testing.T) {
+	list, err := getTaskListFromYaml(exampleYAML)
+	if err != nil {
+		t.Fatalf(`could not test Validate: %v`, err)
+	}
+	if problems := list.Validate(); len(problems) != 0 {
+		t.Fatalf(`expected no problems; found %v`, problems)
+	}
+}
@@ -0,0 +1,91 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// varPattern matches ${NAME}, along with $${NAME}, which is how
+// to write a literal ${NAME}.
+var varPattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// resolveVars works out the value of each variable. The overrides
+// (from the command line) come first, then variables in the
+// process environment with the same names as the ones in the task
+// file, then the task file's own values.
+func resolveVars(file, overrides map[string]string) map[string]string {
+	vars := make(map[string]string, len(file)+len(overrides))
+	for name, value := range file {
+		if env, ok := os.LookupEnv(name); ok {
+			value = env
+		}
+		vars[name] = value
+	}
+	for name, value := range overrides {
+		vars[name] = value
+	}
+	return vars
+}
+
+// interpolate replaces each ${NAME} in text with the value of the
+// variable NAME. The names of any variables that aren't defined
+// are added to undefined, and left as they are.
+func interpolate(text string, vars map[string]string, undefined map[string]bool) string {
+	return varPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if strings.HasPrefix(match, `$$`) {
+			return match[1:]
+		}
+		name := match[2 : len(match)-1]
+		value, ok := vars[name]
+		if !ok {
+			undefined[name] = true
+			return match
+		}
+		return value
+	})
+}
+
+// interpolate replaces the variables in the Task's Command, Args,
+// Environment values, WorkingDirectory, Sources and Generates. It
+// returns the names of any that aren't defined.
+func (s *Task) interpolate(vars map[string]string) map[string]bool {
+	undefined := make(map[string]bool)
+	s.Command = interpolate(s.Command, vars, undefined)
+	for i, arg := range s.Args {
+		s.Args[i] = interpolate(arg, vars, undefined)
+	}
+	for key, value := range s.Environment {
+		s.Environment[key] = interpolate(value, vars, undefined)
+	}
+	s.WorkingDirectory = interpolate(s.WorkingDirectory, vars, undefined)
+	for i, pattern := range s.Sources {
+		s.Sources[i] = interpolate(pattern, vars, undefined)
+	}
+	for i, pattern := range s.Generates {
+		s.Generates[i] = interpolate(pattern, vars, undefined)
+	}
+	return undefined
+}
+
+// undefinedVarsError lists the variables that Tasks use but that
+// aren't defined, along with the Tasks that use each of them.
+func undefinedVarsError(usedBy map[string][]string) error {
+	names := make([]string, 0, len(usedBy))
+	for name := range usedBy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := make([]string, len(names))
+	for i, name := range names {
+		tasks := usedBy[name]
+		sort.Strings(tasks)
+		for j, task := range tasks {
+			tasks[j] = fmt.Sprintf(`%q`, task)
+		}
+		entries[i] = fmt.Sprintf(`%s (used by %s)`, name, strings.Join(tasks, `, `))
+	}
+	return fmt.Errorf(`undefined variables: %s`, strings.Join(entries, `, `))
+}
@@ -0,0 +1,72 @@
+package task
+
+import (
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+var varsYAML = `---
+vars:
+  ENV: development
+  OUT: build
+workingDirectory: ${OUT}
+Build:
+  command: make
+  args: ["${OUT}/app", "ENV=${ENV}", "$${ENV}"]
+  environment:
+    RAILS_ENV: ${ENV}
+  sources: ["${OUT}/**/*.go"]
+  generates: ["${OUT}/app"]
+`
+
+func TestVars(t *testing.T) {
+	t.Setenv(`OUT`, `dist`)
+	file := &TaskFile{Overrides: map[string]string{`ENV`: `staging`}}
+	if err := yaml.Unmarshal([]byte(varsYAML), file); err != nil {
+		t.Fatalf(`could not test vars: %v`, err)
+	}
+	build := file.Tasks[`Build`]
+	expected := []string{`dist/app`, `ENV=staging`, `${ENV}`}
+	for i, arg := range expected {
+		if actual := build.Args[i]; actual != arg {
+			t.Fatalf(`expected argument %d to be %q; was %q`, i, arg, actual)
+		}
+	}
+	if actual := build.Environment[`RAILS_ENV`]; actual != `staging` {
+		t.Fatalf(`expected RAILS_ENV to be "staging"; was %q`, actual)
+	}
+	if actual := build.WorkingDirectory; actual != `dist` {
+		t.Fatalf(`expected the default working directory to be "dist"; was %q`, actual)
+	}
+	if actual := build.Sources[0]; actual != `dist/**/*.go` {
+		t.Fatalf(`expected sources to be "dist/**/*.go"; was %q`, actual)
+	}
+	if actual := build.Generates[0]; actual != `dist/app` {
+		t.Fatalf(`expected generates to be "dist/app"; was %q`, actual)
+	}
+}
+
+func TestUndefinedVars(t *testing.T) {
+	_, err := getTaskListFromYaml(`---
+vars:
+  ENV: development
+Build:
+  command: make
+  args: ["${OUT}/app"]
+Deploy:
+  command: ./deploy
+  args: ["${ENV}"]
+  environment:
+    REGION: ${REGION}
+    OUT: ${OUT}
+`)
+	if err == nil {
+		t.Fatalf(`expected an error for undefined variables`)
+	}
+	expected := `undefined variables: OUT (used by "Build", "Deploy"), REGION (used by "Deploy")`
+	if actual := err.Error(); !strings.Contains(actual, expected) {
+		t.Fatalf(`expected the error to list the undefined variables as %q; was %q`, expected, actual)
+	}
+}
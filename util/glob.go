@@ -0,0 +1,110 @@
+package util
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// globPattern turns a glob into a regular expression over slash-
+// separated paths. Besides the wildcards of filepath.Match, "**"
+// matches any number of directories, including none.
+func globPattern(glob string) (*regexp.Regexp, error) {
+	if _, err := filepath.Match(glob, ``); err != nil {
+		return nil, err
+	}
+	b := new(strings.Builder)
+	b.WriteString(`^`)
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if strings.HasPrefix(glob[i:], `**/`) {
+				b.WriteString(`(?:.*/)?`)
+				i += 2
+			} else if strings.HasPrefix(glob[i:], `**`) {
+				b.WriteString(`.*`)
+				i++
+			} else {
+				b.WriteString(`[^/]*`)
+			}
+		case '?':
+			b.WriteString(`[^/]`)
+		case '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				return nil, filepath.ErrBadPattern
+			}
+			b.WriteString(glob[i : i+end+1])
+			i += end
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+				b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(`$`)
+	return regexp.Compile(b.String())
+}
+
+// ValidGlob reports whether Glob would accept the pattern.
+func ValidGlob(glob string) error {
+	_, err := globPattern(glob)
+	return err
+}
+
+// Glob returns the names of the files matching the pattern, like
+// filepath.Glob, but "**" matches any number of directories,
+// including none, so "src/**/*.go" matches every Go file under
+// src. Directories themselves aren't returned.
+func Glob(glob string) ([]string, error) {
+	glob = filepath.ToSlash(filepath.Clean(glob))
+	pattern, err := globPattern(glob)
+	if err != nil {
+		return nil, err
+	}
+	// Only walk the part of the tree that can match: the
+	// directories before the first wildcard.
+	root := glob
+	if i := strings.IndexAny(root, `*?[\`); i >= 0 {
+		root = root[:i]
+	} else {
+		// No wildcards: just the one file, if it's there.
+		path := filepath.FromSlash(glob)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return []string{path}, nil
+		}
+		return []string{}, nil
+	}
+	if i := strings.LastIndexByte(root, '/'); i >= 0 {
+		root = root[:i+1]
+	} else {
+		root = ``
+	}
+	walkRoot := filepath.FromSlash(root)
+	if walkRoot == `` {
+		walkRoot = `.`
+	}
+	matches := make([]string, 0)
+	err = filepath.WalkDir(walkRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == walkRoot {
+				// Nothing there to match.
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if pattern.MatchString(filepath.ToSlash(path)) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return matches, err
+}
@@ -0,0 +1,47 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{`main.go`, `README.md`, `src/a.go`, `src/b.txt`, `src/deep/er/c.go`} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf(`could not set up test files: %v`, err)
+		}
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf(`could not set up test files: %v`, err)
+		}
+	}
+	expect := func(glob string, expected ...string) {
+		matches, err := Glob(filepath.Join(dir, glob))
+		if err != nil {
+			t.Fatalf(`could not glob %q: %v`, glob, err)
+		}
+		for i, match := range matches {
+			matches[i] = filepath.ToSlash(strings.TrimPrefix(match, dir+string(filepath.Separator)))
+		}
+		if strings.Join(matches, `, `) != strings.Join(expected, `, `) {
+			t.Fatalf(`expected %q to match %q; matched %q`, glob, expected, matches)
+		}
+	}
+	expect(`*.go`, `main.go`)
+	expect(`src/*`, `src/a.go`, `src/b.txt`)
+	expect(`**/*.go`, `main.go`, `src/a.go`, `src/deep/er/c.go`)
+	expect(`src/**/*.go`, `src/a.go`, `src/deep/er/c.go`)
+	expect(`src/**`, `src/a.go`, `src/b.txt`, `src/deep/er/c.go`)
+	expect(`[A-Z]*.md`, `README.md`)
+	expect(`missing/**/*.go`)
+	expect(`README.md`, `README.md`)
+	expect(`src`)
+
+	if err := ValidGlob(`src/[a-`); err == nil {
+		t.Fatalf(`expected an unclosed character class to be invalid`)
+	}
+}
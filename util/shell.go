@@ -0,0 +1,23 @@
+package util
+
+import (
+	"regexp"
+	"strings"
+)
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// ShellQuote joins the words into a single command line that a
+// POSIX shell would split back into the same words, quoting the
+// ones that need it.
+func ShellQuote(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		if shellSafe.MatchString(word) {
+			quoted[i] = word
+		} else {
+			quoted[i] = `'` + strings.ReplaceAll(word, `'`, `'"'"'`) + `'`
+		}
+	}
+	return strings.Join(quoted, ` `)
+}
@@ -0,0 +1,18 @@
+package util
+
+import "testing"
+
+func TestShellQuote(t *testing.T) {
+	expect := func(expected string, input ...string) {
+		actual := ShellQuote(input)
+		if actual != expected {
+			t.Fatalf(`ShellQuote %q should have been %q; was %q`, input, expected, actual)
+		}
+	}
+
+	expect(`git pull`, `git`, `pull`)
+	expect(`rake assets:precompile`, `rake`, `assets:precompile`)
+	expect(`sh -c 'echo hello; exit 1'`, `sh`, `-c`, `echo hello; exit 1`)
+	expect(`echo ''`, `echo`, ``)
+	expect(`echo 'it'"'"'s'`, `echo`, `it's`)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// validate is the "validate" command. It checks the task file for
+// problems and prints them, without running any of the tasks.
+func validate(args []string) {
+	flags := flag.NewFlagSet(`validate`, flag.ExitOnError)
+	flags.Usage = printUsage
+	flags.Var(&vars, `v`, varsUsage)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		printUsage()
+		os.Exit(-1)
+	}
+	yamlFile := flags.Arg(0)
+	file, source := loadTaskFile(yamlFile)
+	if printProblems(yamlFile, source, file.Tasks) {
+		os.Exit(1)
+	}
+	fmt.Printf("%s: %d tasks, no problems found\n", yamlFile, len(file.Tasks))
+}